@@ -7,13 +7,14 @@ import (
 	"time"
 
 	"github.com/stripe/stripe-cli/pkg/cmd"
+	"github.com/stripe/stripe-cli/pkg/config"
 	"github.com/stripe/stripe-cli/pkg/stripe"
 )
 
 func main() {
 	ctx := context.Background()
 
-	if stripe.TelemetryOptedOut(os.Getenv("STRIPE_CLI_TELEMETRY_OPTOUT")) || stripe.TelemetryOptedOut(os.Getenv("DO_NOT_TRACK")) {
+	if telemetryOptedOut() {
 		// Proceed without the telemetry client if client opted out.
 		cmd.Execute(ctx)
 	} else {
@@ -30,3 +31,18 @@ func main() {
 		telemetryClient.Wait()
 	}
 }
+
+// telemetryOptedOut returns whether telemetry should be disabled. It's
+// disabled by default in CI, unless STRIPE_CLI_TELEMETRY_OPTOUT is explicitly
+// set.
+func telemetryOptedOut() bool {
+	if stripe.TelemetryOptedOut(os.Getenv("DO_NOT_TRACK")) {
+		return true
+	}
+
+	if optout, ok := os.LookupEnv("STRIPE_CLI_TELEMETRY_OPTOUT"); ok {
+		return stripe.TelemetryOptedOut(optout)
+	}
+
+	return config.IsCI()
+}
@@ -10,10 +10,13 @@ type configCmd struct {
 	cmd    *cobra.Command
 	config *config.Config
 
-	list  bool
-	edit  bool
-	unset string
-	set   bool
+	list     bool
+	edit     bool
+	unset    string
+	set      bool
+	group    string
+	debugEnv bool
+	explain  bool
 }
 
 func newConfigCmd() *configCmd {
@@ -26,6 +29,9 @@ func newConfigCmd() *configCmd {
 		Long: `config lets you set and unset specific configuration values for your profile if
 you need more granular control over the configuration.`,
 		Example: `stripe config --list
+  stripe config --list --group prod
+  stripe config --debug-env
+  stripe config --explain
   stripe config --set color off
   stripe config --unset color`,
 		RunE: cc.runConfigCmd,
@@ -35,6 +41,9 @@ you need more granular control over the configuration.`,
 	cc.cmd.Flags().BoolVarP(&cc.edit, "edit", "e", false, "Open an editor to the config file")
 	cc.cmd.Flags().StringVar(&cc.unset, "unset", "", "Unset a specific config field")
 	cc.cmd.Flags().BoolVar(&cc.set, "set", false, "Set a config field to some value")
+	cc.cmd.Flags().StringVar(&cc.group, "group", "", "Only list the profiles in this group")
+	cc.cmd.Flags().BoolVar(&cc.explain, "explain", false, "Show the value of the main config fields for your profile and where each one comes from")
+	cc.cmd.Flags().BoolVar(&cc.debugEnv, "debug-env", false, "List the environment variables that can affect the config and their values")
 
 	cc.cmd.Flags().SetInterspersed(false) // allow args to happen after flags to enable 2 arguments to --set
 
@@ -47,10 +56,17 @@ func (cc *configCmd) runConfigCmd(cmd *cobra.Command, args []string) error {
 		return cc.config.Profile.WriteConfigField(args[0], args[1])
 	case cc.unset != "":
 		return cc.config.Profile.DeleteConfigField(cc.unset)
+	case cc.list && cc.group != "":
+		return cc.config.PrintGroup(cc.group)
 	case cc.list:
 		return cc.config.PrintConfig()
 	case cc.edit:
 		return cc.config.EditConfig()
+	case cc.explain:
+		return cc.config.PrintExplanation()
+	case cc.debugEnv:
+		cc.config.PrintEnv()
+		return nil
 	default:
 		// no flags set or unrecognized flags/args
 		return cc.cmd.Help()
@@ -60,10 +60,15 @@ func (fc *FixturesCmd) runFixturesCmd(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	stripeAccount := fc.stripeAccount
+	if stripeAccount == "" {
+		stripeAccount = fc.Cfg.Profile.GetDefaultConnectAccount()
+	}
+
 	fixture, err := fixtures.NewFixtureFromFile(
 		afero.NewOsFs(),
 		apiKey,
-		fc.stripeAccount,
+		stripeAccount,
 		stripe.DefaultAPIBaseURL,
 		args[0],
 		fc.skip,
@@ -75,7 +80,12 @@ func (fc *FixturesCmd) runFixturesCmd(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	_, err = fixture.Execute(cmd.Context(), fc.apiVersion)
+	apiVersion := fc.apiVersion
+	if apiVersion == "" {
+		apiVersion = fc.Cfg.Profile.GetAPIVersion()
+	}
+
+	_, err = fixture.Execute(cmd.Context(), apiVersion)
 
 	if err != nil {
 		return err
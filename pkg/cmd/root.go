@@ -60,6 +60,16 @@ var rootCmd = &cobra.Command{
 		telemetryMetadata.SetMerchant(merchant)
 		telemetryMetadata.SetUserAgent(useragent.GetEncodedUserAgent())
 
+		// recording when the profile was used is a best effort, it shouldn't
+		// prevent the command from running either
+		if usesProfileKeys(cmd) {
+			if err := Config.Profile.Touch(); err != nil {
+				log.WithFields(log.Fields{
+					"prefix": "cmd.rootCmd.PersistentPreRun",
+				}).Debugf("Failed to record profile usage: %s", err)
+			}
+		}
+
 		// plugins send their own telemetry due to having richer context than the CLI does
 		if !plugins.IsPluginCommand(cmd) {
 			// record command invocation
@@ -68,6 +78,30 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// profileKeyCategories are the annotations of the top level commands that
+// make requests with the keys of the profile
+var profileKeyCategories = map[string]bool{
+	"http":      true,
+	"webhooks":  true,
+	"resource":  true,
+	"namespace": true,
+}
+
+// usesProfileKeys returns whether cmd makes requests with the keys of the
+// profile, as opposed to commands such as version, completion or config that
+// only work locally.
+func usesProfileKeys(cmd *cobra.Command) bool {
+	root := cmd.Root()
+
+	for ; cmd.HasParent(); cmd = cmd.Parent() {
+		if cmd.Parent() == root {
+			return profileKeyCategories[root.Annotations[cmd.Name()]] || cmd.Name() == "logs"
+		}
+	}
+
+	return false
+}
+
 func sendCommandInvocationEvent(ctx context.Context) {
 	telemetryClient := stripe.GetTelemetryClient(ctx)
 	if telemetryClient != nil {
@@ -69,3 +69,23 @@ func TestExampleCommands(t *testing.T) {
 		require.Equal(t, err.Error(), "`stripe samples create` accepts at maximum 2 positional arguments. See `stripe samples create --help` for supported flags and usage")
 	}
 }
+
+func TestUsesProfileKeys(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"get"}, true},
+		{[]string{"listen"}, true},
+		{[]string{"customers", "list"}, true},
+		{[]string{"logs", "tail"}, true},
+		{[]string{"version"}, false},
+		{[]string{"completion"}, false},
+		{[]string{"config"}, false},
+		{[]string{"serve"}, false},
+	} {
+		cmd, _, err := rootCmd.Find(tt.args)
+		require.NoError(t, err, tt.args)
+		require.Equal(t, tt.want, usesProfileKeys(cmd), tt.args)
+	}
+}
@@ -1,52 +1,776 @@
 package cmd
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"html/template"
+	"io"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	exec "golang.org/x/sys/execabs"
+	"golang.org/x/term"
 
+	"github.com/stripe/stripe-cli/pkg/serve"
 	"github.com/stripe/stripe-cli/pkg/validators"
 )
 
 type serveCmd struct {
 	cmd *cobra.Command
+
+	port          string
+	delay         time.Duration
+	delayJitter   time.Duration
+	injectPK      bool
+	behindProxy   bool
+	noSniff       bool
+	hosts         []string
+	configFile    string
+	listingTmpl   string
+	methods       []string
+	fromTar       string
+	fromZip       string
+	shutdownAfter time.Duration
+	maxRequests   int
+	onRequest     string
+	https         bool
+	certHosts     []string
+	sourceMaps    bool
+	verboseErrors bool
+	errorPage500  string
+	banner        bool
+	rateLimit     string
+	bandwidth     string
+	injectBase    string
+	har           string
+	requiredFiles []string
+	tarEndpoint   string
+	printSRI      bool
+	printTree     bool
+	pager         bool
+	tlsMinVersion string
+	clientCA      string
+	certFile      string
+	keyFile       string
+	stripComps    int
+	download      bool
+	downloadExts  []string
+	proxies       []string
+	pathDelays    []string
+	accessLogFile string
+	noRanges      bool
+	noConditional bool
+	cacheFiles    bool
+	maxAgeByExt   map[string]int
+	json          bool
+	redirectCode  int
+	indexFiles    []string
+	spa           bool
+	spaStatus     int
+	httpPort      string
+	generateIndex bool
 }
 
-func newServeCmd() *serveCmd {
-	var port string
+// serveStartupEvent is printed with --json once the server is listening
+type serveStartupEvent struct {
+	Address string `json:"address"`
+	Dir     string `json:"dir"`
+	HTTPS   bool   `json:"https"`
+
+	// HTTPAddress is the plain HTTP address when listening on --http-port too
+	HTTPAddress string `json:"http_address,omitempty"`
+}
+
+// serveConfigFiles are the files looked up in the served directory to provide
+// default values for the serve flags
+var serveConfigFiles = []string{"serve.yaml", ".stripeserve"}
 
+func newServeCmd() *serveCmd {
 	sc := &serveCmd{}
 
 	sc.cmd = &cobra.Command{
 		Use:     "serve",
 		Aliases: []string{"srv"},
 		Short:   "Serve static files locally",
+		Long: `Serve static files locally.
+
+When serving a directory, only the files inside of it are ever served: paths
+with .. components and symbolic links pointing outside of the directory get a
+403 Forbidden.`,
 		Args:    validators.MaximumNArgs(1),
 		Example: "stripe serve /path/to/directory",
-		RunE: func(cmd *cobra.Command, args []string) error {
-			dir := "."
-			if len(args) == 1 {
-				dir = args[0]
-			}
+		RunE:    sc.runServeCmd,
+	}
+
+	sc.cmd.Flags().StringVar(&sc.port, "port", "4242", "Provide a custom port to serve content from.")
+	sc.cmd.Flags().DurationVar(&sc.delay, "delay", 0, "Wait for the given duration before serving each response, e.g. 500ms")
+	sc.cmd.Flags().DurationVar(&sc.delayJitter, "delay-jitter", 0, "Add a random extra delay of up to the given duration to each response")
+	sc.cmd.Flags().StringArrayVar(&sc.pathDelays, "path-delay", []string{}, "Wait longer before serving the requests under a path prefix, on top of --delay, e.g. /slow=2s (can be repeated)")
+	sc.cmd.Flags().StringVar(&sc.injectBase, "inject-base", "", "Add a <base> tag with this path to the <head> of served HTML files, e.g. /app/ when served under a subpath")
+	sc.cmd.Flags().BoolVar(&sc.injectPK, "inject-pk", false, fmt.Sprintf("Replace %s in served HTML files with your test mode publishable key", serve.PublishableKeyPlaceholder))
+	sc.cmd.Flags().BoolVar(&sc.behindProxy, "behind-proxy", false, "Trust the X-Forwarded-For and X-Real-IP headers when logging the client address")
+	sc.cmd.Flags().BoolVar(&sc.noSniff, "nosniff", false, "Set the X-Content-Type-Options: nosniff header on all responses")
+	sc.cmd.Flags().StringToIntVar(&sc.maxAgeByExt, "max-age-by-ext", map[string]int{}, "Set the Cache-Control max-age in seconds of files by extension, with * for the other extensions, e.g. js=31536000,html=0,*=60")
+	sc.cmd.Flags().BoolVar(&sc.cacheFiles, "cache-files", false, "Keep the contents of files smaller than 1MB in memory, up to 64MB in total, instead of reading them on every request")
+	sc.cmd.Flags().StringSliceVar(&sc.indexFiles, "index-fallback-order", []string{"index.html"}, "The files served for directories, in order of preference, e.g. index.html,200.html,index.htm")
+	sc.cmd.Flags().BoolVar(&sc.spa, "spa", false, "Serve index.html for the paths that don't exist, for single page applications")
+	sc.cmd.Flags().IntVar(&sc.spaStatus, "spa-status", http.StatusOK, "The status code of the index.html responses served by --spa, 200 or 404")
+	sc.cmd.Flags().IntVar(&sc.redirectCode, "redirect-code", http.StatusMovedPermanently, "The status code of the redirects adding a trailing slash to directories, e.g. 302 for temporary redirects")
+	sc.cmd.Flags().BoolVar(&sc.noRanges, "no-ranges", false, "Ignore the Range header of requests and always serve whole files, with Accept-Ranges: none")
+	sc.cmd.Flags().BoolVar(&sc.noConditional, "no-conditional", false, "Ignore the If-Modified-Since and If-None-Match headers of requests and always serve the current files")
+	sc.cmd.Flags().StringArrayVar(&sc.requiredFiles, "require-file", []string{}, "Exit with an error at startup if this file doesn't exist in the served directory (can be repeated)")
+	sc.cmd.Flags().StringArrayVar(&sc.hosts, "require-host", []string{}, "Only serve requests for this Host header, others get a 421 (can be repeated)")
+	sc.cmd.Flags().StringVar(&sc.configFile, "serve-config", "", fmt.Sprintf("Read default flag values from this YAML file (default: %s in the served directory)", strings.Join(serveConfigFiles, " or ")))
+	sc.cmd.Flags().StringVar(&sc.listingTmpl, "listing-template", "", "Render directory listings with this Go HTML template file")
+	sc.cmd.Flags().BoolVar(&sc.generateIndex, "generate-index", false, "Serve a generated page listing the files of directories without an index.html")
+	sc.cmd.Flags().StringSliceVar(&sc.methods, "methods", []string{http.MethodGet, http.MethodHead}, "A comma-separated list of HTTP methods to serve, others get a 405")
+	sc.cmd.Flags().StringVar(&sc.fromTar, "from-tar", "", "Serve the contents of a .tar or .tar.gz archive instead of a directory")
+	sc.cmd.Flags().StringVar(&sc.fromZip, "from-zip", "", "Serve the contents of a .zip archive instead of a directory")
+	sc.cmd.Flags().IntVar(&sc.stripComps, "strip-components", 0, "Remove this many leading path components from the served files, e.g. 1 to serve dist/index.html at /index.html")
+	sc.cmd.Flags().DurationVar(&sc.shutdownAfter, "shutdown-after", 0, "Stop the server once no request has been received for the given duration, e.g. 10m")
+	sc.cmd.Flags().StringVar(&sc.onRequest, "on-request", "", "POST a JSON description of every request to this URL")
+	sc.cmd.Flags().IntVar(&sc.maxRequests, "max-requests", 0, "Stop the server once it has successfully served the given number of requests")
+	sc.cmd.Flags().BoolVar(&sc.https, "https", false, "Serve over HTTPS, using a generated self-signed certificate unless --cert is set")
+	sc.cmd.Flags().StringVar(&sc.httpPort, "http-port", "", "With --https, also serve plain HTTP on this port")
+	sc.cmd.Flags().StringVar(&sc.certFile, "cert", "", "Serve the PEM encoded certificate in this file with --https instead of a generated one, reloading it when it changes")
+	sc.cmd.Flags().StringVar(&sc.keyFile, "key", "", "The PEM encoded private key of the --cert certificate")
+	sc.cmd.Flags().StringArrayVar(&sc.certHosts, "cert-host", []string{}, "Add a hostname or IP address to the generated certificate, in addition to localhost (can be repeated)")
+	sc.cmd.Flags().StringVar(&sc.tlsMinVersion, "tls-min-version", "1.2", "The minimum TLS version accepted with --https, either 1.2 or 1.3")
+	sc.cmd.Flags().StringVar(&sc.clientCA, "client-ca", "", "Require clients to present a certificate signed by one of the CA certificates in this PEM file, with --https")
+	sc.cmd.Flags().BoolVar(&sc.sourceMaps, "source-maps", false, "Set the SourceMap header on JavaScript files that have a .map file next to them")
+	sc.cmd.Flags().BoolVar(&sc.verboseErrors, "verbose-errors", false, "Include the underlying error and file path in 5xx responses. This may reveal local paths, so only use it for development")
+	sc.cmd.Flags().StringVar(&sc.errorPage500, "error-page-500", "", "HTML file served as the body of 500 responses")
+	sc.cmd.Flags().BoolVar(&sc.download, "download", false, "Make browsers download the served files instead of displaying them")
+	sc.cmd.Flags().StringSliceVar(&sc.downloadExts, "download-ext", []string{}, "A comma-separated list of extensions of the files to download instead of displaying, e.g. pdf,zip")
+	sc.cmd.Flags().StringArrayVar(&sc.proxies, "proxy", []string{}, "Forward the requests under a path prefix to another server, including websockets, e.g. /api=http://localhost:3000 (can be repeated)")
+	sc.cmd.Flags().StringVar(&sc.accessLogFile, "access-log-file", "", "Append the access logs to this file instead of printing them, and reopen it on SIGHUP")
+	sc.cmd.Flags().BoolVar(&sc.banner, "banner", true, "Print the served directory and address on startup")
+	sc.cmd.Flags().BoolVar(&sc.json, "json", false, "Print the served directory and address as a JSON object instead of the banner once the server is listening")
+	sc.cmd.Flags().StringVar(&sc.tarEndpoint, "tar-endpoint", "", "Serve all the files as a gzipped tarball at this path, e.g. /_download.tar.gz")
+	sc.cmd.Flags().StringVar(&sc.har, "har", "", "Record the requests served, with their status, size and timing, to this HAR file when the server stops")
+	sc.cmd.Flags().StringVar(&sc.bandwidth, "bandwidth", "", "Limit the speed at which each response is sent, e.g. 100kb/s, to simulate a slow connection")
+	sc.cmd.Flags().StringVar(&sc.rateLimit, "rate-limit", "", "Limit the rate of requests per client IP address, e.g. 10/s, and respond with a 429 beyond it")
+	sc.cmd.Flags().BoolVar(&sc.printSRI, "print-sri", false, "Print the sha384 Subresource Integrity hashes of the served JavaScript and CSS files on startup")
+	sc.cmd.Flags().BoolVar(&sc.printTree, "print-tree", false, "Print the tree of the served files on startup")
+	sc.cmd.Flags().BoolVar(&sc.pager, "pager", false, "Page the output of --print-tree through $PAGER, or less, when stdout is a terminal")
+
+	return sc
+}
+
+func (sc *serveCmd) runServeCmd(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+
+	absoluteDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	err = sc.loadConfigFile(cmd, absoluteDir)
+	if err != nil {
+		return err
+	}
+
+	// Checked once the serve config is loaded, since it can set the ports too.
+	// Port 0 lets the system pick a free port, which --json reports.
+	for _, port := range []struct{ flag, value string }{
+		{"port", sc.port},
+		{"http-port", sc.httpPort},
+	} {
+		if (port.value == "" && port.flag == "http-port") || port.value == "0" {
+			continue
+		}
+
+		err = validators.Port(port.value)
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %w", port.flag, err)
+		}
+
+		num, _ := strconv.Atoi(port.value)
+		err = serve.CheckPortPermission(num)
+		if err != nil {
+			return fmt.Errorf("invalid --%s: %w", port.flag, err)
+		}
+	}
+
+	dirFS := serve.NewDirWrapper(absoluteDir)
+	var fs http.FileSystem = serve.HideFiles(dirFS, sc.hiddenConfigFiles(absoluteDir))
+	source := fmt.Sprintf("directory  %s", absoluteDir)
+	servedPath := absoluteDir
+
+	if (sc.fromTar != "" || sc.fromZip != "") && len(args) == 1 {
+		return fmt.Errorf("a directory can't be served at the same time as an archive")
+	}
+
+	switch {
+	case sc.fromTar != "" && sc.fromZip != "":
+		return fmt.Errorf("only one of --from-tar and --from-zip can be used")
+	case sc.fromTar != "":
+		tarFS, err := serve.OpenTar(sc.fromTar)
+		if err != nil {
+			return err
+		}
+		fs = http.FS(tarFS)
+		source = fmt.Sprintf("archive  %s", sc.fromTar)
+		servedPath = sc.fromTar
+	case sc.fromZip != "":
+		zipFS, err := zip.OpenReader(sc.fromZip)
+		if err != nil {
+			return err
+		}
+		defer zipFS.Close()
+		fs = http.FS(zipFS)
+		source = fmt.Sprintf("archive  %s", sc.fromZip)
+		servedPath = sc.fromZip
+	}
+
+	if sc.stripComps != 0 {
+		fs, err = serve.StripComponents(fs, sc.stripComps)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = serve.CheckRequiredFiles(fs, sc.requiredFiles)
+	if err != nil {
+		return err
+	}
+
+	err = serve.ValidateRedirectCode(sc.redirectCode)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Flags().Changed("spa-status") && !sc.spa {
+		return fmt.Errorf("--spa-status can only be used with --spa")
+	}
+
+	if sc.pager && !sc.printTree {
+		return fmt.Errorf("--pager can only be used with --print-tree")
+	}
+
+	err = serve.ValidateSPAStatus(sc.spaStatus)
+	if err != nil {
+		return err
+	}
+
+	err = serve.ValidateIndexFiles(sc.indexFiles)
+	if err != nil {
+		return err
+	}
 
-			absoluteDir, err := filepath.Abs(dir)
+	var tlsConfig *tls.Config
+
+	if sc.https {
+		minVersion, err := serve.ParseTLSVersion(sc.tlsMinVersion)
+		if err != nil {
+			return err
+		}
+
+		tlsConfig = &tls.Config{
+			MinVersion: minVersion,
+		}
+
+		switch {
+		case (sc.certFile == "") != (sc.keyFile == ""):
+			return fmt.Errorf("--cert and --key must be used together")
+		case sc.certFile != "" && len(sc.certHosts) > 0:
+			return fmt.Errorf("--cert-host can't be used with --cert")
+		case sc.certFile != "":
+			reloader, err := serve.NewCertReloader(sc.certFile, sc.keyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load the certificate: %w", err)
+			}
+			tlsConfig.GetCertificate = reloader.GetCertificate
+		default:
+			cert, err := serve.SelfSignedCertificate(sc.certHosts)
 			if err != nil {
 				return err
 			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		if sc.clientCA != "" {
+			// Clients without a certificate could use the plain HTTP server instead
+			if sc.httpPort != "" {
+				return fmt.Errorf("--client-ca can't be used with --http-port")
+			}
+
+			tlsConfig.ClientCAs, err = serve.LoadClientCAs(sc.clientCA)
+			if err != nil {
+				return fmt.Errorf("failed to load the client CA certificates: %w", err)
+			}
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if len(sc.certHosts) > 0 {
+		return fmt.Errorf("--cert-host can only be used with --https")
+	} else if sc.certFile != "" || sc.keyFile != "" {
+		return fmt.Errorf("--cert and --key can only be used with --https")
+	} else if cmd.Flags().Changed("tls-min-version") {
+		return fmt.Errorf("--tls-min-version can only be used with --https")
+	} else if sc.httpPort != "" {
+		return fmt.Errorf("--http-port can only be used with --https")
+	} else if sc.clientCA != "" {
+		return fmt.Errorf("--client-ca can only be used with --https")
+	}
+
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+
+	if sc.banner && !sc.json {
+		fmt.Printf("Starting server for %s\n", source)
+		fmt.Println("Starting static file server at address", fmt.Sprintf("%s://localhost:%s", scheme, sc.port))
+		if sc.httpPort != "" {
+			fmt.Println("Starting static file server at address", fmt.Sprintf("http://localhost:%s", sc.httpPort))
+		}
+	}
+
+	if sc.printSRI {
+		hashes, err := serve.SRIHashes(fs)
+		if err != nil {
+			return fmt.Errorf("failed to compute SRI hashes: %w", err)
+		}
+
+		for _, hash := range hashes {
+			fmt.Printf("%s  %s\n", hash.Integrity, hash.Path)
+		}
+	}
+
+	if sc.printTree {
+		var tree bytes.Buffer
+		err := serve.PrintTree(&tree, fs, dir)
+		if err != nil {
+			return fmt.Errorf("failed to list the served files: %w", err)
+		}
+
+		if sc.pager {
+			page(tree.Bytes())
+		} else {
+			os.Stdout.Write(tree.Bytes())
+		}
+	}
 
-			fmt.Printf("Starting server for directory  %s\n", absoluteDir)
+	ctx, stop := context.WithCancel(withSIGTERMCancel(cmd.Context(), func() {}))
+	defer stop()
 
-			fmt.Println("Starting static file server at address", fmt.Sprintf("http://localhost:%s", port))
-			http.Handle("/", http.FileServer(http.Dir(absoluteDir)))
-			return http.ListenAndServe(fmt.Sprintf(":%s", port), handlers.LoggingHandler(os.Stdout, http.DefaultServeMux))
+	cfg := &serve.Config{
+		Delay:              sc.delay,
+		DelayJitter:        sc.delayJitter,
+		NoSniff:            sc.noSniff,
+		RequiredHosts:      sc.hosts,
+		Methods:            sc.methods,
+		SourceMaps:         sc.sourceMaps,
+		Download:           sc.download,
+		NoRanges:           sc.noRanges,
+		NoConditional:      sc.noConditional,
+		CacheFiles:         sc.cacheFiles,
+		MaxAgeByExt:        sc.maxAgeByExt,
+		RedirectCode:       sc.redirectCode,
+		IndexFiles:         sc.indexFiles,
+		SPA:                sc.spa,
+		SPAStatus:          sc.spaStatus,
+		DownloadExtensions: sc.downloadExts,
+		VerboseErrors:      sc.verboseErrors,
+		IdleTimeout:        sc.shutdownAfter,
+		OnIdle: func() {
+			fmt.Printf("No requests received for %s, stopping server\n", sc.shutdownAfter)
+			stop()
+		},
+		MaxRequests: sc.maxRequests,
+		OnMaxRequests: func() {
+			fmt.Printf("Served %d requests, stopping server\n", sc.maxRequests)
+			stop()
 		},
 	}
 
-	sc.cmd.Flags().StringVar(&port, "port", "4242", "Provide a custom port to serve content from.")
+	// Archives can't disappear once they're opened
+	if sc.fromTar == "" && sc.fromZip == "" {
+		cfg.Available = dirFS.Available
+	}
 
-	return sc
+	if sc.onRequest != "" {
+		notifyURL, err := serve.ParseNotifyURL(sc.onRequest)
+		if err != nil {
+			return err
+		}
+		cfg.NotifyURL = notifyURL
+	}
+
+	if sc.tarEndpoint != "" {
+		err = serve.ValidateTarEndpoint(sc.tarEndpoint)
+		if err != nil {
+			return err
+		}
+		cfg.TarEndpoint = sc.tarEndpoint
+	}
+
+	if sc.injectBase != "" {
+		cfg.BaseHref, err = serve.ParseBaseHref(sc.injectBase)
+		if err != nil {
+			return err
+		}
+	}
+
+	if sc.bandwidth != "" {
+		cfg.Bandwidth, err = serve.ParseBandwidth(sc.bandwidth)
+		if err != nil {
+			return err
+		}
+	}
+
+	if sc.rateLimit != "" {
+		rate, err := serve.ParseRate(sc.rateLimit)
+		if err != nil {
+			return err
+		}
+		cfg.RateLimit = &rate
+	}
+
+	for _, value := range sc.pathDelays {
+		pathDelay, err := serve.ParsePathDelay(value)
+		if err != nil {
+			return err
+		}
+		cfg.PathDelays = append(cfg.PathDelays, pathDelay)
+	}
+
+	for _, value := range sc.proxies {
+		proxy, err := serve.ParseProxy(value)
+		if err != nil {
+			return err
+		}
+		cfg.Proxies = append(cfg.Proxies, proxy)
+	}
+
+	switch {
+	case sc.listingTmpl != "" && sc.generateIndex:
+		return fmt.Errorf("only one of --listing-template and --generate-index can be used")
+	case sc.listingTmpl != "":
+		cfg.ListingTemplate, err = template.ParseFiles(sc.listingTmpl)
+		if err != nil {
+			return err
+		}
+	case sc.generateIndex:
+		cfg.ListingTemplate = serve.DefaultListingTemplate
+	}
+
+	if sc.errorPage500 != "" {
+		if sc.verboseErrors {
+			return fmt.Errorf("only one of --verbose-errors and --error-page-500 can be used")
+		}
+
+		cfg.ErrorPage500, err = os.ReadFile(sc.errorPage500)
+		if err != nil {
+			return fmt.Errorf("failed to read the 500 error page: %w", err)
+		}
+	}
+
+	if sc.injectPK {
+		// Leave the placeholder in place if there's no key to inject
+		if publishableKey, err := Config.Profile.GetPublishableKey(false); err == nil {
+			cfg.PublishableKey = publishableKey
+		}
+	}
+
+	if sc.har != "" {
+		cfg.HAR = serve.NewHARRecorder()
+	}
+
+	handler := serve.NewHandler(fs, cfg)
+
+	var accessLog io.Writer = os.Stdout
+
+	if sc.accessLogFile != "" {
+		logFile, err := serve.OpenAccessLog(sc.accessLogFile)
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+
+		reopenOnSIGHUP(ctx, logFile)
+		accessLog = logFile
+	}
+
+	withLogging := func(accessLog io.Writer) http.Handler {
+		logged := handlers.LoggingHandler(accessLog, handler)
+
+		// Only trust the forwarding headers when explicitly asked to, since
+		// any client talking directly to the server can set them
+		if sc.behindProxy {
+			logged = handlers.ProxyHeaders(logged)
+		}
+
+		return logged
+	}
+
+	var servers []*http.Server
+	var listeners []net.Listener
+	defer func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	}()
+
+	listen := func(port string, accessLog io.Writer, tlsConfig *tls.Config) (int, error) {
+		server := serve.NewServer(fmt.Sprintf(":%s", port), withLogging(accessLog))
+		server.TLSConfig = tlsConfig
+
+		ln, err := serve.Listen(server)
+		if err != nil {
+			return 0, err
+		}
+
+		servers = append(servers, server)
+		listeners = append(listeners, ln)
+
+		return ln.Addr().(*net.TCPAddr).Port, nil
+	}
+
+	event := serveStartupEvent{
+		Dir:   servedPath,
+		HTTPS: tlsConfig != nil,
+	}
+
+	if sc.httpPort == "" {
+		port, err := listen(sc.port, accessLog, tlsConfig)
+		if err != nil {
+			return err
+		}
+		event.Address = fmt.Sprintf("%s://localhost:%d", scheme, port)
+	} else {
+		// Tell the logs of both servers apart
+		port, err := listen(sc.port, &linePrefixWriter{w: accessLog, prefix: "[https] "}, tlsConfig)
+		if err != nil {
+			return err
+		}
+		event.Address = fmt.Sprintf("https://localhost:%d", port)
+
+		port, err = listen(sc.httpPort, &linePrefixWriter{w: accessLog, prefix: "[http] "}, nil)
+		if err != nil {
+			return err
+		}
+		event.HTTPAddress = fmt.Sprintf("http://localhost:%d", port)
+	}
+
+	if sc.json {
+		err = json.NewEncoder(os.Stdout).Encode(event)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = serveAll(ctx, stop, servers, listeners)
+
+	if cfg.HAR != nil {
+		if harErr := cfg.HAR.WriteFile(sc.har); harErr != nil && err == nil {
+			err = fmt.Errorf("failed to write the HAR file: %w", harErr)
+		}
+	}
+
+	return err
+}
+
+// page writes output to stdout through $PAGER, or less, when stdout is a
+// terminal, and directly otherwise or if the pager can't be started.
+func page(output []byte) {
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		pager := strings.Fields(os.Getenv("PAGER"))
+		if len(pager) == 0 {
+			pager = []string{"less"}
+		}
+
+		cmd := exec.Command(pager[0], pager[1:]...)
+		cmd.Stdin = bytes.NewReader(output)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Start(); err == nil {
+			cmd.Wait() // #nosec G104
+			return
+		}
+	}
+
+	os.Stdout.Write(output)
+}
+
+// serveAll runs each server on its listener until one of them stops, then
+// stops the others by calling stop, which must cancel ctx. The first error
+// returned by a server is returned.
+func serveAll(ctx context.Context, stop func(), servers []*http.Server, listeners []net.Listener) error {
+	errCh := make(chan error, len(servers))
+
+	for i := range servers {
+		go func(server *http.Server, ln net.Listener) {
+			errCh <- serve.Serve(ctx, server, ln)
+		}(servers[i], listeners[i])
+	}
+
+	var firstErr error
+	for range servers {
+		if err := <-errCh; err != nil && firstErr == nil {
+			firstErr = err
+		}
+		stop()
+	}
+
+	return firstErr
+}
+
+// linePrefixWriter prefixes what is written to w, which must be written a
+// line at a time like handlers.LoggingHandler does.
+type linePrefixWriter struct {
+	w      io.Writer
+	prefix string
+}
+
+func (l *linePrefixWriter) Write(p []byte) (int, error) {
+	_, err := l.w.Write(append([]byte(l.prefix), p...))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// reopenOnSIGHUP reopens the access log file when the process receives a
+// SIGHUP, until ctx is done, so that the file can be rotated.
+func reopenOnSIGHUP(ctx context.Context, logFile *serve.AccessLog) {
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hupCh)
+
+		for {
+			select {
+			case <-hupCh:
+				if err := logFile.Reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to reopen the access log: %v\n", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// hiddenConfigFiles returns the paths, relative to dir, of the serve config
+// files that may be in dir, so that they aren't served along with the other
+// files.
+func (sc *serveCmd) hiddenConfigFiles(dir string) []string {
+	hidden := append([]string{}, serveConfigFiles...)
+
+	if sc.configFile != "" {
+		configFile, err := filepath.Abs(sc.configFile)
+		if err == nil {
+			rel, err := filepath.Rel(dir, configFile)
+			if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				hidden = append(hidden, filepath.ToSlash(rel))
+			}
+		}
+	}
+
+	return hidden
+}
+
+// loadConfigFile sets the flags that weren't passed on the command line to the
+// values found in the serve config file, if there is one.
+func (sc *serveCmd) loadConfigFile(cmd *cobra.Command, dir string) error {
+	path := sc.configFile
+	if path == "" {
+		for _, name := range serveConfigFiles {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+	}
+
+	if path == "" {
+		return nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+
+	err := v.ReadInConfig()
+	if err != nil {
+		return fmt.Errorf("failed to read serve config %s: %w", path, err)
+	}
+
+	// Map options such as max-age-by-ext are flattened by AllKeys, so only
+	// look at the top level keys
+	settings := v.AllSettings()
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Flags passed on the command line take precedence
+	passed := make(map[string]bool)
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		passed[flag.Name] = true
+	})
+
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || name == "serve-config" {
+			return fmt.Errorf("unknown option %q in serve config %s", name, path)
+		}
+
+		if passed[name] {
+			continue
+		}
+
+		var values []string
+		switch value := settings[name].(type) {
+		case []interface{}:
+			for _, item := range value {
+				values = append(values, fmt.Sprint(item))
+			}
+		case map[string]interface{}:
+			pairs := make([]string, 0, len(value))
+			for key, item := range value {
+				pairs = append(pairs, fmt.Sprintf("%s=%v", key, item))
+			}
+			sort.Strings(pairs)
+			values = []string{strings.Join(pairs, ",")}
+		default:
+			values = []string{fmt.Sprint(value)}
+		}
+
+		// Set through the flag set so that the flag is marked as changed, like
+		// when it's passed on the command line
+		for _, value := range values {
+			err = cmd.Flags().Set(name, value)
+			if err != nil {
+				return fmt.Errorf("invalid value %q for %s in serve config %s: %w", value, name, path, err)
+			}
+		}
+	}
+
+	return nil
 }
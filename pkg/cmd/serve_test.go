@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "serve.yaml"), []byte(`port: 5555
+delay: 10ms
+require-host:
+  - a.test
+  - b.test
+max-age-by-ext:
+  js: 31536000
+  "*": 60
+`), 0600)
+	require.NoError(t, err)
+
+	sc := newServeCmd()
+	require.NoError(t, sc.cmd.ParseFlags([]string{"--port", "6666"}))
+
+	err = sc.loadConfigFile(sc.cmd, dir)
+	require.NoError(t, err)
+
+	require.Equal(t, "6666", sc.port)
+	require.Equal(t, 10*time.Millisecond, sc.delay)
+	require.Equal(t, []string{"a.test", "b.test"}, sc.hosts)
+	require.Equal(t, map[string]int{"js": 31536000, "*": 60}, sc.maxAgeByExt)
+
+	// Options set by the config file count as passed, e.g. for --spa-status
+	require.True(t, sc.cmd.Flags().Changed("delay"))
+}
+
+func TestServeHiddenConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	sc := newServeCmd()
+	require.Equal(t, []string{"serve.yaml", ".stripeserve"}, sc.hiddenConfigFiles(dir))
+
+	sc.configFile = filepath.Join(dir, "conf", "serve.yml")
+	require.Equal(t, []string{"serve.yaml", ".stripeserve", "conf/serve.yml"}, sc.hiddenConfigFiles(dir))
+
+	sc.configFile = filepath.Join(filepath.Dir(dir), "serve.yml")
+	require.Equal(t, []string{"serve.yaml", ".stripeserve"}, sc.hiddenConfigFiles(dir))
+}
+
+func TestServeLoadConfigFileUnknownOption(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, ".stripeserve"), []byte("nope: true\n"), 0600)
+	require.NoError(t, err)
+
+	sc := newServeCmd()
+
+	err = sc.loadConfigFile(sc.cmd, dir)
+	require.EqualError(t, err, `unknown option "nope" in serve config `+filepath.Join(dir, ".stripeserve"))
+}
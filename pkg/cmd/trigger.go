@@ -80,7 +80,17 @@ func (tc *triggerCmd) runTriggerCmd(cmd *cobra.Command, args []string) error {
 
 	event := args[0]
 
-	_, err = fixtures.Trigger(cmd.Context(), event, tc.stripeAccount, tc.apiBaseURL, apiKey, tc.skip, tc.override, tc.add, tc.remove, tc.raw, tc.apiVersion)
+	apiVersion := tc.apiVersion
+	if apiVersion == "" {
+		apiVersion = Config.Profile.GetAPIVersion()
+	}
+
+	stripeAccount := tc.stripeAccount
+	if stripeAccount == "" {
+		stripeAccount = Config.Profile.GetDefaultConnectAccount()
+	}
+
+	_, err = fixtures.Trigger(cmd.Context(), event, stripeAccount, tc.apiBaseURL, apiKey, tc.skip, tc.override, tc.add, tc.remove, tc.raw, apiVersion)
 	if err != nil {
 		return err
 	}
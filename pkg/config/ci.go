@@ -0,0 +1,44 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// ciEnvVars are environment variables set by common CI providers. Generic
+// names such as BUILD_NUMBER are left out since they're used outside of CI too.
+var ciEnvVars = []string{
+	"CI",
+	"CONTINUOUS_INTEGRATION",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"CIRCLECI",
+	"TRAVIS",
+	"BUILDKITE",
+	"JENKINS_URL",
+	"TEAMCITY_VERSION",
+	"TF_BUILD",
+	"BITBUCKET_BUILD_NUMBER",
+	"CODEBUILD_BUILD_ID",
+}
+
+// IsCI returns whether the CLI appears to be running in a continuous
+// integration environment, where it shouldn't color its output or prompt for
+// input by default. A variable explicitly set to false or 0 is ignored.
+func IsCI() bool {
+	for _, name := range ciEnvVars {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(value) {
+		case "false", "0":
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+// clearCIEnv unsets the CI environment variables for the duration of the test
+func clearCIEnv(t *testing.T) {
+	for _, name := range ciEnvVars {
+		if value, ok := os.LookupEnv(name); ok {
+			os.Unsetenv(name)
+			t.Cleanup(func() { os.Setenv(name, value) })
+		}
+	}
+}
+
+func TestIsCI(t *testing.T) {
+	clearCIEnv(t)
+	require.False(t, IsCI())
+
+	t.Setenv("CI", "false")
+	require.False(t, IsCI())
+
+	t.Setenv("BUILD_NUMBER", "42")
+	t.Setenv("RUN_ID", "42")
+	require.False(t, IsCI())
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	require.True(t, IsCI())
+}
+
+func TestGetColorInCI(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	clearCIEnv(t)
+
+	p := Profile{ProfileName: "tests"}
+
+	color, err := p.GetColor()
+	require.NoError(t, err)
+	require.Equal(t, ColorAuto, color)
+
+	t.Setenv("BUILDKITE", "true")
+
+	color, err = p.GetColor()
+	require.NoError(t, err)
+	require.Equal(t, ColorOff, color)
+
+	// An explicit setting still wins
+	viper.Set("tests.color", ColorAuto)
+
+	color, err = p.GetColor()
+	require.NoError(t, err)
+	require.Equal(t, ColorAuto, color)
+}
@@ -6,7 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -31,6 +31,16 @@ const ColorOff = "off"
 // ColorAuto represents the auto-state for colors
 const ColorAuto = "auto"
 
+// ConfigDirPermissions is the mode used when creating the directory holding
+// the config file. It is restrictive by default because the config file
+// contains test mode secret keys, and can be changed before InitConfig is
+// called, e.g. to let a group read the config on a shared machine.
+var ConfigDirPermissions os.FileMode = 0700
+
+// ConfigFilePermissions is the mode used when writing the config file. Like
+// ConfigDirPermissions, it can be changed before InitConfig is called.
+var ConfigFilePermissions os.FileMode = 0600
+
 // IConfig allows us to add more implementations, such as ones for unit tests
 type IConfig interface {
 	GetProfile() *Profile
@@ -117,11 +127,16 @@ func (c *Config) InitConfig() {
 		c.ProfilesFile = configFile
 		viper.SetConfigType("toml")
 		viper.SetConfigFile(configFile)
-		viper.SetConfigPermissions(os.FileMode(0600))
+		viper.SetConfigPermissions(ConfigFilePermissions)
 
 		// Try to change permissions manually, because we used to create files
-		// with default permissions (0644)
-		err := os.Chmod(configFile, os.FileMode(0600))
+		// with default permissions (0644) and directories with 0755
+		err := os.Chmod(configFile, ConfigFilePermissions)
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatalf("%s", err)
+		}
+
+		err = os.Chmod(configFolder, ConfigDirPermissions)
 		if err != nil && !os.IsNotExist(err) {
 			log.Fatalf("%s", err)
 		}
@@ -136,12 +151,8 @@ func (c *Config) InitConfig() {
 	}
 
 	if c.Profile.DeviceName == "" {
-		deviceName, err := os.Hostname()
-		if err != nil {
-			deviceName = "unknown"
-		}
-
-		c.Profile.DeviceName = deviceName
+		c.Profile.DeviceName = c.Profile.defaultDeviceName()
+		c.Profile.deviceNameDefault = true
 	}
 
 	color, err := c.Profile.GetColor()
@@ -171,35 +182,24 @@ func (c *Config) InitConfig() {
 	// c.Profile.redactAllLivemodeValues()
 }
 
-// EditConfig opens the configuration file in the default editor.
+// EditConfig opens the configuration file in the editor returned by
+// Profile.GetEditor.
 func (c *Config) EditConfig() error {
-	var err error
+	editor, err := c.Profile.GetEditor()
+	if err != nil {
+		return err
+	}
 
 	fmt.Println("Opening config file:", c.ProfilesFile)
 
-	switch runtime.GOOS {
-	case "darwin", "linux":
-		editor := os.Getenv("EDITOR")
-		if editor == "" {
-			editor = "vi"
-		}
+	args := editorArgs(editor)
+	cmd := exec.Command(args[0], append(args[1:], c.ProfilesFile)...)
+	// Some editors detect whether they have control of stdin/out and will
+	// fail if they do not.
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
 
-		cmd := exec.Command(editor, c.ProfilesFile)
-		// Some editors detect whether they have control of stdin/out and will
-		// fail if they do not.
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-
-		return cmd.Run()
-	case "windows":
-		// As far as I can tell, Windows doesn't have an easily accesible or
-		// comparable option to $EDITOR, so default to notepad for now
-		err = exec.Command("notepad", c.ProfilesFile).Run()
-	default:
-		err = fmt.Errorf("unsupported platform")
-	}
-
-	return err
+	return cmd.Run()
 }
 
 // PrintConfig outputs the contents of the configuration file.
@@ -225,6 +225,31 @@ func (c *Config) PrintConfig() error {
 	return nil
 }
 
+// PrintGroup outputs the fields of the profiles in the given group.
+func (c *Config) PrintGroup(group string) error {
+	names := ListProfilesByGroup()[group]
+	if len(names) == 0 {
+		return fmt.Errorf("no profiles found in group %q", group)
+	}
+
+	for _, name := range names {
+		configs := viper.GetStringMapString(name)
+
+		fields := make([]string, 0, len(configs))
+		for field := range configs {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		fmt.Printf("[%s]\n", name)
+		for _, field := range fields {
+			fmt.Printf("  %s=%s\n", field, configs[field])
+		}
+	}
+
+	return nil
+}
+
 // GetInstalledPlugins returns a list of locally installed plugins.
 // This does not vary by profile
 func (c *Config) GetInstalledPlugins() []string {
@@ -241,6 +266,8 @@ func (c *Config) RemoveProfile(profileName string) error {
 
 	for field, value := range runtimeViper.AllSettings() {
 		if isProfile(value) && field == profileName {
+			(&Profile{ProfileName: field}).deleteLivemodeValues(livemodeFields...)
+
 			runtimeViper, err = removeKey(runtimeViper, field)
 			if err != nil {
 				return err
@@ -258,6 +285,8 @@ func (c *Config) RemoveAllProfiles() error {
 
 	for field, value := range runtimeViper.AllSettings() {
 		if isProfile(value) {
+			(&Profile{ProfileName: field}).deleteLivemodeValues(livemodeFields...)
+
 			runtimeViper, err = removeKey(runtimeViper, field)
 			if err != nil {
 				return err
@@ -281,7 +310,14 @@ func (c *Config) WriteConfigField(field string, value interface{}) error {
 	runtimeViper := viper.GetViper()
 	runtimeViper.Set(field, value)
 
-	return runtimeViper.WriteConfig()
+	err := runtimeViper.WriteConfig()
+	if err != nil {
+		return err
+	}
+
+	notifyConfigChange("", field, ConfigChangeWrite)
+
+	return nil
 }
 
 // syncConfig merges a runtimeViper instance with the config file being used.
@@ -291,6 +327,7 @@ func syncConfig(runtimeViper *viper.Viper) error {
 	runtimeViper.SetConfigFile(profilesFile)
 	// Ensure we preserve the config file type
 	runtimeViper.SetConfigType(filepath.Ext(profilesFile))
+	runtimeViper.SetConfigPermissions(ConfigFilePermissions)
 
 	err := runtimeViper.WriteConfig()
 	if err != nil {
@@ -330,7 +367,7 @@ func makePath(path string) error {
 	dir := filepath.Dir(path)
 
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		err = os.MkdirAll(dir, os.ModePerm)
+		err = os.MkdirAll(dir, ConfigDirPermissions)
 		if err != nil {
 			return err
 		}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/spf13/viper"
@@ -18,3 +20,76 @@ func TestRemoveKey(t *testing.T) {
 	require.EqualValues(t, []string{"stay"}, nv.AllKeys())
 	require.ElementsMatch(t, []string{"stay", "remove"}, v.AllKeys())
 }
+
+func TestConfigPermissionsNewPaths(t *testing.T) {
+	profilesFile := filepath.Join(t.TempDir(), "stripe", "config.toml")
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigFile(profilesFile)
+	viper.SetConfigType("toml")
+
+	p := Profile{ProfileName: "tests", DeviceName: "st-testing"}
+	require.NoError(t, p.writeProfile(viper.New()))
+
+	info, err := os.Stat(filepath.Dir(profilesFile))
+	require.NoError(t, err)
+	require.Equal(t, ConfigDirPermissions, info.Mode().Perm())
+
+	info, err = os.Stat(profilesFile)
+	require.NoError(t, err)
+	require.Equal(t, ConfigFilePermissions, info.Mode().Perm())
+}
+
+func TestConfigPermissionsCustom(t *testing.T) {
+	defer func(dir, file os.FileMode) {
+		ConfigDirPermissions, ConfigFilePermissions = dir, file
+	}(ConfigDirPermissions, ConfigFilePermissions)
+	ConfigDirPermissions, ConfigFilePermissions = 0750, 0640
+
+	profilesFile := filepath.Join(t.TempDir(), "stripe", "config.toml")
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigFile(profilesFile)
+	viper.SetConfigType("toml")
+
+	p := Profile{ProfileName: "tests", DeviceName: "st-testing"}
+	require.NoError(t, p.writeProfile(viper.New()))
+
+	info, err := os.Stat(filepath.Dir(profilesFile))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0750), info.Mode().Perm())
+
+	info, err = os.Stat(profilesFile)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+}
+
+func TestConfigPermissionsTightened(t *testing.T) {
+	defer func() { KeyRing = nil }()
+	viper.Reset()
+	defer viper.Reset()
+
+	xdgConfigHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdgConfigHome)
+
+	configFolder := filepath.Join(xdgConfigHome, "stripe")
+	require.NoError(t, os.Mkdir(configFolder, 0755))
+	require.NoError(t, os.Chmod(configFolder, 0755))
+
+	configFile := filepath.Join(configFolder, "config.toml")
+	require.NoError(t, os.WriteFile(configFile, []byte("[default]\ndevice_name = 'st-testing'\n"), 0644))
+	require.NoError(t, os.Chmod(configFile, 0644))
+
+	c := &Config{Color: "auto", LogLevel: "info"}
+	c.InitConfig()
+
+	info, err := os.Stat(configFolder)
+	require.NoError(t, err)
+	require.Equal(t, ConfigDirPermissions, info.Mode().Perm())
+
+	info, err = os.Stat(configFile)
+	require.NoError(t, err)
+	require.Equal(t, ConfigFilePermissions, info.Mode().Perm())
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DeviceNameTemplateName is the config field holding the template used to
+// generate a device name when none is set
+const DeviceNameTemplateName = "device_name_template"
+
+var deviceNamePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// defaultDeviceName returns the device name to use when none is configured:
+// the expansion of the profile's device name template if it has one, or the
+// hostname otherwise.
+func (p *Profile) defaultDeviceName() string {
+	template := viper.GetString(p.GetConfigField(DeviceNameTemplateName))
+	if template != "" {
+		deviceName, err := expandDeviceNameTemplate(template)
+		if err == nil {
+			return deviceName
+		}
+
+		log.WithFields(log.Fields{
+			"prefix": "config.Profile.defaultDeviceName",
+		}).Warnf("Ignoring %s: %s", DeviceNameTemplateName, err)
+	}
+
+	return hostname()
+}
+
+// expandDeviceNameTemplate replaces the {hostname}, {user} and {pid}
+// placeholders in template.
+func expandDeviceNameTemplate(template string) (string, error) {
+	var expandErr error
+
+	deviceName := deviceNamePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch placeholder {
+		case "{hostname}":
+			return hostname()
+		case "{user}":
+			if u, err := user.Current(); err == nil {
+				return u.Username
+			}
+			return os.Getenv("USER")
+		case "{pid}":
+			return strconv.Itoa(os.Getpid())
+		default:
+			expandErr = fmt.Errorf("unknown placeholder %s, expected one of {hostname}, {user}, {pid}", placeholder)
+			return placeholder
+		}
+	})
+
+	if expandErr != nil {
+		return "", expandErr
+	}
+
+	if deviceName == "" {
+		return "", fmt.Errorf("template expands to an empty device name")
+	}
+
+	return deviceName, nil
+}
+
+// SanitizeDeviceName makes name safe to register webhook endpoints with, by
+// only keeping alphanumerics, dashes and underscores. Accents are removed from
+// letters and other characters are replaced by dashes, so that "Zoë's
+// MacBook Pro" becomes "Zoes-MacBook-Pro". The result is empty if name has no
+// characters worth keeping.
+func SanitizeDeviceName(name string) string {
+	var b strings.Builder
+	dash := false
+
+	for _, r := range norm.NFKD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r) || r == '\'' || r == '’':
+			// Diacritics left by the decomposition and apostrophes are
+			// dropped rather than replaced, for readability
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-'):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		default:
+			dash = true
+		}
+	}
+
+	return b.String()
+}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+
+	return name
+}
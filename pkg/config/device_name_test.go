@@ -0,0 +1,39 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandDeviceNameTemplate(t *testing.T) {
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	deviceName, err := expandDeviceNameTemplate("ci-runner-{hostname}-{pid}")
+	require.NoError(t, err)
+	require.Equal(t, "ci-runner-"+host+"-"+strconv.Itoa(os.Getpid()), deviceName)
+
+	_, err = expandDeviceNameTemplate("ci-runner-{host}")
+	require.EqualError(t, err, "unknown placeholder {host}, expected one of {hostname}, {user}, {pid}")
+}
+
+func TestSanitizeDeviceName(t *testing.T) {
+	for name, want := range map[string]string{
+		"st-testing":         "st-testing",
+		"  my_laptop  ":      "my_laptop",
+		"Zoë's MacBook Pro":  "Zoes-MacBook-Pro",
+		"Zoë’s MacBook Pro":  "Zoes-MacBook-Pro",
+		"dev.local":          "dev-local",
+		"tab\there\nnewline": "tab-here-newline",
+		"emoji 🚀 box":        "emoji-box",
+		"---":                "---",
+		"ｆｕｌｌｗｉｄｔｈ":          "fullwidth",
+		"日本語":                "",
+		"  \t ":              "",
+	} {
+		require.Equal(t, want, SanitizeDeviceName(name), name)
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+var sectionHeaderRegexp = regexp.MustCompile(`^\[\s*([^\[\]]+?)\s*\]\s*(#.*)?$`)
+
+// configLine is a key/value line of the config file
+type configLine struct {
+	section string
+	key     string
+	value   interface{}
+	number  int
+}
+
+// scanConfigFile returns the key/value lines of the TOML file at path along
+// with the section they belong to, and the sections in the order they
+// appear in, including repeated ones. Keys of the root table have an empty
+// section.
+//
+// Only the subset of TOML written by the CLI is supported: files with arrays
+// of tables, dotted keys or table names, or values spanning several lines such
+// as multi-line strings and arrays are refused rather than misread.
+func scanConfigFile(path string) ([]configLine, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var lines []configLine
+	var sections []string
+	section := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") {
+			return nil, nil, fmt.Errorf("%s:%d: arrays of tables are not supported", path, number)
+		}
+
+		if match := sectionHeaderRegexp.FindStringSubmatch(line); match != nil {
+			if !isQuoted(match[1]) && strings.Contains(match[1], ".") {
+				return nil, nil, fmt.Errorf("%s:%d: dotted table names are not supported", path, number)
+			}
+
+			section = strings.Trim(match[1], `"'`)
+			sections = append(sections, section)
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, nil, fmt.Errorf("%s:%d: unable to parse line", path, number)
+		}
+
+		// Values spanning several lines can't be decoded on their own
+		var decoded map[string]interface{}
+		_, err := toml.Decode(line, &decoded)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: unable to parse line, values spanning several lines are not supported: %w", path, number, err)
+		}
+
+		key := strings.Trim(strings.TrimSpace(parts[0]), `"'`)
+		value, ok := decoded[key]
+		if !ok || len(decoded) != 1 {
+			return nil, nil, fmt.Errorf("%s:%d: dotted keys are not supported", path, number)
+		}
+
+		lines = append(lines, configLine{
+			section: section,
+			key:     key,
+			value:   value,
+			number:  number,
+		})
+	}
+
+	return lines, sections, scanner.Err()
+}
+
+// DetectDuplicateKeys reads the config file at path and returns the sections
+// and keys that are defined more than once, which viper resolves
+// unpredictably. Sections are reported as [name] and keys as section.key.
+func DetectDuplicateKeys(path string) ([]string, error) {
+	lines, sections, err := scanConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var duplicates []string
+	seen := make(map[string]int)
+
+	for _, section := range sections {
+		seen["["+section+"]"]++
+		if seen["["+section+"]"] == 2 {
+			duplicates = append(duplicates, "["+section+"]")
+		}
+	}
+
+	for _, line := range lines {
+		name := line.key
+		if line.section != "" {
+			name = line.section + "." + line.key
+		}
+
+		seen[name]++
+		if seen[name] == 2 {
+			duplicates = append(duplicates, name)
+		}
+	}
+
+	return duplicates, nil
+}
+
+// DedupeConfig rewrites the config file at path so that repeated sections are
+// merged and, for keys defined more than once, only the last value is kept.
+// Comments are not preserved. Files using TOML constructs that
+// scanConfigFile doesn't support are left untouched and an error is returned.
+func DedupeConfig(path string) error {
+	lines, _, err := scanConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	config := make(map[string]interface{})
+
+	for _, line := range lines {
+		table := config
+		if line.section != "" {
+			sectionTable, ok := config[line.section].(map[string]interface{})
+			if !ok {
+				sectionTable = make(map[string]interface{})
+				config[line.section] = sectionTable
+			}
+			table = sectionTable
+		}
+
+		table[line.key] = line.value
+	}
+
+	buf := new(bytes.Buffer)
+	err = toml.NewEncoder(buf).Encode(config)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), ConfigFilePermissions)
+}
+
+// isQuoted checks whether name is a quoted TOML key
+func isQuoted(name string) bool {
+	return len(name) >= 2 && (name[0] == '"' || name[0] == '\'') && name[len(name)-1] == name[0]
+}
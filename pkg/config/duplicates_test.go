@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const duplicatedConfig = `color = 'auto'
+
+[default]
+device_name = 'first'
+test_mode_api_key = 'sk_test_1234567890abcd'
+
+# hand-edited
+[tests]
+device_name = 'st-testing'
+
+[default]
+device_name = 'second'
+display_name = 'My Account'
+`
+
+func TestDetectDuplicateKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, []byte(duplicatedConfig), 0600))
+
+	duplicates, err := DetectDuplicateKeys(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"[default]", "default.device_name"}, duplicates)
+}
+
+func TestDedupeConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, []byte(duplicatedConfig), 0600))
+
+	require.NoError(t, DedupeConfig(path))
+
+	duplicates, err := DetectDuplicateKeys(path)
+	require.NoError(t, err)
+	require.Empty(t, duplicates)
+
+	require.Equal(t, `color = "auto"
+
+[default]
+  device_name = "second"
+  display_name = "My Account"
+  test_mode_api_key = "sk_test_1234567890abcd"
+
+[tests]
+  device_name = "st-testing"
+`, string(helperLoadBytes(t, path)))
+}
+
+func TestDedupeConfigUnsupported(t *testing.T) {
+	for _, contents := range []string{
+		"[[plugins]]\nname = 'apps'\n",
+		"[a.b]\nkey = 'value'\n",
+		"[default]\nhosts = [\n  'a=b',\n]\n",
+		"[default]\nnote = \"\"\"\nkey = 'value'\n\"\"\"\n",
+		"[default]\nsub.key = 'value'\n",
+	} {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+
+		require.Error(t, DedupeConfig(path), contents)
+		require.Equal(t, contents, string(helperLoadBytes(t, path)))
+	}
+
+	// Quoted names with dots are plain names
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, []byte("[\"a.b\"]\n\"c.d\" = 'value'\nhosts = ['a', 'b']\n"), 0600))
+	require.NoError(t, DedupeConfig(path))
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	exec "golang.org/x/sys/execabs"
+
+	"github.com/spf13/viper"
+)
+
+// EditorName is the config field holding the command used to edit files,
+// which may include arguments such as `code --wait`
+const EditorName = "editor"
+
+// defaultEditor returns the editor used when none is configured
+func defaultEditor() string {
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+
+	return "vi"
+}
+
+// GetEditor returns the command used to open files for editing: the editor
+// field of the profile first, then the VISUAL and EDITOR environment
+// variables, and finally vi, or notepad on Windows. An error is returned if
+// the program of the chosen command can't be found in the PATH.
+func (p *Profile) GetEditor() (string, error) {
+	editor, source := defaultEditor(), "the default editor"
+
+	for _, candidate := range []struct{ value, source string }{
+		{viper.GetString(p.GetConfigField(EditorName)), "the editor config field"},
+		{os.Getenv("VISUAL"), "$VISUAL"},
+		{os.Getenv("EDITOR"), "$EDITOR"},
+	} {
+		if strings.TrimSpace(candidate.value) != "" {
+			editor, source = strings.TrimSpace(candidate.value), candidate.source
+			break
+		}
+	}
+
+	program := editorArgs(editor)[0]
+	if _, err := exec.LookPath(program); err != nil {
+		return "", fmt.Errorf("the editor %s set by %s can't be found in your PATH", program, source)
+	}
+
+	return editor, nil
+}
+
+// editorArgs splits an editor command into the program and its arguments.
+// The whole command is used as the program when it can be found, so that
+// paths with spaces such as C:\Program Files\Notepad++\notepad++.exe work
+// without quoting.
+func editorArgs(editor string) []string {
+	if _, err := exec.LookPath(editor); err == nil {
+		return []string{editor}
+	}
+
+	return strings.Fields(editor)
+}
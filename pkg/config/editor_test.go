@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEditor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake editors aren't executable on Windows")
+	}
+
+	bin := t.TempDir()
+	for _, name := range []string{"vi", "nano", "code", "emacs"} {
+		require.NoError(t, os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"), 0700))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(bin, "My Editor"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "My Editor", "edit"), []byte("#!/bin/sh\n"), 0700))
+	t.Setenv("PATH", bin)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+
+	defer WithTempConfig(t, `[configured]
+editor = 'code --wait'
+
+[spaces]
+editor = '`+filepath.Join(bin, "My Editor", "edit")+`'
+
+[missing]
+editor = 'subl'
+`)()
+
+	p := &Profile{ProfileName: "default"}
+
+	editor, err := p.GetEditor()
+	require.NoError(t, err)
+	require.Equal(t, "vi", editor)
+
+	t.Setenv("EDITOR", "nano")
+	editor, err = p.GetEditor()
+	require.NoError(t, err)
+	require.Equal(t, "nano", editor)
+
+	t.Setenv("VISUAL", "emacs")
+	editor, err = p.GetEditor()
+	require.NoError(t, err)
+	require.Equal(t, "emacs", editor)
+
+	editor, err = (&Profile{ProfileName: "configured"}).GetEditor()
+	require.NoError(t, err)
+	require.Equal(t, "code --wait", editor)
+
+	editor, err = (&Profile{ProfileName: "spaces"}).GetEditor()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(bin, "My Editor", "edit"), editor)
+	require.Equal(t, []string{editor}, editorArgs(editor))
+	require.Equal(t, []string{"code", "--wait"}, editorArgs("code --wait"))
+
+	_, err = (&Profile{ProfileName: "missing"}).GetEditor()
+	require.EqualError(t, err, "the editor subl set by the editor config field can't be found in your PATH")
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsultedEnvVars(t *testing.T) {
+	names := ConsultedEnvVars()
+
+	require.True(t, sort.StringsAreSorted(names))
+	require.Contains(t, names, "STRIPE_API_KEY")
+	require.Contains(t, names, "STRIPE_DEVICE_NAME")
+	require.Contains(t, names, "GITHUB_ACTIONS")
+
+	// The result is a copy that callers can modify
+	names[0] = "MODIFIED"
+	require.NotContains(t, ConsultedEnvVars(), "MODIFIED")
+}
+
+func TestRedactURLUserinfo(t *testing.T) {
+	require.Equal(t, "http://****@proxy.local:3128", redactURLUserinfo("http://user:p@ss@proxy.local:3128"))
+	require.Equal(t, "****@proxy.local:3128/path@x", redactURLUserinfo("token@proxy.local:3128/path@x"))
+	require.Equal(t, "http://proxy.local:3128", redactURLUserinfo("http://proxy.local:3128"))
+	require.Equal(t, "localhost,.stripe.com", redactURLUserinfo("localhost,.stripe.com"))
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ConfigSource is the resolved value of a config field and where it came from
+type ConfigSource struct {
+	Field string
+	// Value is the resolved value, with secrets redacted. It's empty when the
+	// field isn't set anywhere and has no default.
+	Value string
+	// Source is one of SourceEnv, SourceConfig, SourceFlag or SourceDefault
+	Source string
+	// Origin is the name of the environment variable or flag, or the path of
+	// the config file, the value came from. It's empty for defaults.
+	Origin string
+}
+
+// ExplainConfig resolves the main fields of the profile the same way the
+// getters do, and reports where each value came from. Besides --device-name
+// and the value of the --color flag given as colorFlag, values given to the
+// Profile struct by flags such as --api-key aren't reported, so only the
+// environment, the config file and the defaults are considered for them.
+func ExplainConfig(profile *Profile, colorFlag string) ([]ConfigSource, error) {
+	err := viper.ReadInConfig()
+	if err != nil && !errors.As(err, &viper.ConfigFileNotFoundError{}) && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	path := viper.ConfigFileUsed()
+
+	fromEnv := func(field, name, value string) ConfigSource {
+		return ConfigSource{Field: field, Value: value, Source: SourceEnv, Origin: name}
+	}
+	fromConfig := func(field, value string) ConfigSource {
+		return ConfigSource{Field: field, Value: value, Source: SourceConfig, Origin: path}
+	}
+	fromDefault := func(field, value string) ConfigSource {
+		return ConfigSource{Field: field, Value: value, Source: SourceDefault}
+	}
+	fromFlag := func(field, name, value string) ConfigSource {
+		return ConfigSource{Field: field, Value: value, Source: SourceFlag, Origin: name}
+	}
+
+	var sources []ConfigSource
+
+	// device_name, which InitConfig defaults to the one derived from the
+	// hostname
+	if value := os.Getenv("STRIPE_DEVICE_NAME"); value != "" {
+		sources = append(sources, fromEnv(DeviceNameName, "STRIPE_DEVICE_NAME", value))
+	} else if profile.DeviceName != "" && !profile.deviceNameDefault {
+		sources = append(sources, fromFlag(DeviceNameName, "--device-name", profile.DeviceName))
+	} else if value := viper.GetString(profile.GetConfigField(DeviceNameName)); value != "" {
+		sources = append(sources, fromConfig(DeviceNameName, value))
+	} else {
+		sources = append(sources, fromDefault(DeviceNameName, profile.DeviceName))
+	}
+
+	// API keys, the environment one only being used for its own mode
+	envKey := os.Getenv("STRIPE_API_KEY")
+	envKeyMode, _ := keyMode(envKey)
+
+	for _, key := range []struct {
+		field string
+		mode  string
+		value string
+	}{
+		{TestModeAPIKeyName, TestMode, firstSet(profile.ProfileName, testModeAPIKeyNames...)},
+		{LiveModeAPIKeyName, LiveMode, viper.GetString(profile.GetConfigField(LiveModeAPIKeyName))},
+	} {
+		switch {
+		case envKey != "" && envKeyMode == key.mode:
+			sources = append(sources, fromEnv(key.field, "STRIPE_API_KEY", redactSecret(envKey)))
+		case key.value != "":
+			sources = append(sources, fromConfig(key.field, redactSecret(key.value)))
+		default:
+			sources = append(sources, fromDefault(key.field, ""))
+		}
+	}
+
+	// output_format, ignoring invalid values like GetOutputFormat
+	if format := strings.ToLower(os.Getenv("STRIPE_OUTPUT_FORMAT")); validateOutputFormat(format) == "" {
+		sources = append(sources, fromEnv(OutputFormatName, "STRIPE_OUTPUT_FORMAT", format))
+	} else if format := strings.ToLower(viper.GetString(profile.GetConfigField(OutputFormatName))); validateOutputFormat(format) == "" {
+		sources = append(sources, fromConfig(OutputFormatName, format))
+	} else {
+		sources = append(sources, fromDefault(OutputFormatName, OutputFormatTable))
+	}
+
+	// api_timeout, ignoring invalid values like GetAPITimeout
+	if timeout, err := parseAPITimeout(os.Getenv("STRIPE_API_TIMEOUT")); err == nil {
+		sources = append(sources, fromEnv(APITimeoutName, "STRIPE_API_TIMEOUT", timeout.String()))
+	} else if timeout, err := parseAPITimeout(viper.GetString(profile.GetConfigField(APITimeoutName))); err == nil {
+		sources = append(sources, fromConfig(APITimeoutName, timeout.String()))
+	} else {
+		sources = append(sources, fromDefault(APITimeoutName, defaultAPITimeout.String()))
+	}
+
+	// color, which can also be set globally or by the --color flag
+	switch {
+	case colorFlag != "":
+		sources = append(sources, fromFlag("color", "--color", colorFlag))
+	case viper.GetString("color") != "":
+		sources = append(sources, fromConfig("color", viper.GetString("color")))
+	case viper.GetString(profile.GetConfigField("color")) != "":
+		sources = append(sources, fromConfig("color", viper.GetString(profile.GetConfigField("color"))))
+	case IsCI():
+		sources = append(sources, fromDefault("color", ColorOff))
+	default:
+		sources = append(sources, fromDefault("color", ColorAuto))
+	}
+
+	// use_keyring, which the environment can only disable
+	if disabled, err := strconv.ParseBool(os.Getenv("STRIPE_DISABLE_KEYRING")); err == nil && disabled {
+		sources = append(sources, fromEnv(UseKeyringName, "STRIPE_DISABLE_KEYRING", "false"))
+	} else if field := profile.GetConfigField(UseKeyringName); viper.IsSet(field) {
+		sources = append(sources, fromConfig(UseKeyringName, strconv.FormatBool(viper.GetBool(field))))
+	} else {
+		sources = append(sources, fromDefault(UseKeyringName, "true"))
+	}
+
+	// Fields only read from the config file
+	for _, field := range []struct{ name, defaultValue string }{
+		{AccountIDName, ""},
+		{APIVersionName, ""},
+		{DefaultConnectAccountName, ""},
+		{DefaultModeName, TestMode},
+		{LiveModeLockedName, "false"},
+		{ConfirmLiveWritesName, "false"},
+	} {
+		if value := viper.GetString(profile.GetConfigField(field.name)); value != "" {
+			sources = append(sources, fromConfig(field.name, value))
+		} else {
+			sources = append(sources, fromDefault(field.name, field.defaultValue))
+		}
+	}
+
+	return sources, nil
+}
+
+// PrintExplanation outputs the resolved fields of the profile along with where
+// each value came from, as returned by ExplainConfig.
+func (c *Config) PrintExplanation() error {
+	sources, err := ExplainConfig(&c.Profile, c.Color)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sources {
+		value := s.Value
+		if value == "" {
+			value = "(not set)"
+		}
+
+		switch s.Source {
+		case SourceDefault:
+			fmt.Printf("%s = %s (default)\n", s.Field, value)
+		default:
+			fmt.Printf("%s = %s (%s %s)\n", s.Field, value, s.Source, s.Origin)
+		}
+	}
+
+	return nil
+}
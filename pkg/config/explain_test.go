@@ -0,0 +1,72 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainConfig(t *testing.T) {
+	defer WithTempConfig(t, `[tests]
+device_name = 'from-config'
+test_mode_api_key = 'sk_test_1234567890abcd'
+output_format = 'json'
+api_timeout = 'soon'
+default_mode = 'live'
+`)()
+
+	t.Setenv("STRIPE_API_KEY", "sk_live_abcdefghijklmnop")
+	t.Setenv("STRIPE_DISABLE_KEYRING", "true")
+
+	// Like InitConfig when --device-name isn't given
+	p := &Profile{ProfileName: "tests", DeviceName: "hostname", deviceNameDefault: true}
+
+	sources, err := ExplainConfig(p, "")
+	require.NoError(t, err)
+
+	byField := explainByField(sources)
+
+	path := viper.ConfigFileUsed()
+
+	require.Equal(t, ConfigSource{Field: DeviceNameName, Value: "from-config", Source: SourceConfig, Origin: path}, byField[DeviceNameName])
+	require.Equal(t, ConfigSource{Field: TestModeAPIKeyName, Value: RedactAPIKey("sk_test_1234567890abcd"), Source: SourceConfig, Origin: path}, byField[TestModeAPIKeyName])
+	require.Equal(t, ConfigSource{Field: LiveModeAPIKeyName, Value: RedactAPIKey("sk_live_abcdefghijklmnop"), Source: SourceEnv, Origin: "STRIPE_API_KEY"}, byField[LiveModeAPIKeyName])
+	require.Equal(t, ConfigSource{Field: OutputFormatName, Value: OutputFormatJSON, Source: SourceConfig, Origin: path}, byField[OutputFormatName])
+	require.Equal(t, ConfigSource{Field: APITimeoutName, Value: "1m20s", Source: SourceDefault}, byField[APITimeoutName])
+	require.Equal(t, ConfigSource{Field: UseKeyringName, Value: "false", Source: SourceEnv, Origin: "STRIPE_DISABLE_KEYRING"}, byField[UseKeyringName])
+	require.Equal(t, ConfigSource{Field: DefaultModeName, Value: LiveMode, Source: SourceConfig, Origin: path}, byField[DefaultModeName])
+	require.Equal(t, ConfigSource{Field: APIVersionName, Source: SourceDefault}, byField[APIVersionName])
+}
+
+func TestExplainConfigFlags(t *testing.T) {
+	defer WithTempConfig(t, `color = 'off'
+
+[tests]
+device_name = 'from-config'
+`)()
+
+	sources, err := ExplainConfig(&Profile{ProfileName: "tests", DeviceName: "from-flag"}, "on")
+	require.NoError(t, err)
+
+	byField := explainByField(sources)
+	require.Equal(t, ConfigSource{Field: DeviceNameName, Value: "from-flag", Source: SourceFlag, Origin: "--device-name"}, byField[DeviceNameName])
+	require.Equal(t, ConfigSource{Field: "color", Value: "on", Source: SourceFlag, Origin: "--color"}, byField["color"])
+
+	// Without the flags, the config file is used, then the hostname default
+	sources, err = ExplainConfig(&Profile{ProfileName: "other", DeviceName: "hostname", deviceNameDefault: true}, "")
+	require.NoError(t, err)
+
+	byField = explainByField(sources)
+	require.Equal(t, ConfigSource{Field: DeviceNameName, Value: "hostname", Source: SourceDefault}, byField[DeviceNameName])
+	require.Equal(t, ConfigSource{Field: "color", Value: "off", Source: SourceConfig, Origin: viper.ConfigFileUsed()}, byField["color"])
+}
+
+func explainByField(sources []ConfigSource) map[string]ConfigSource {
+	byField := make(map[string]ConfigSource)
+	for _, source := range sources {
+		byField[source.Field] = source
+	}
+
+	return byField
+}
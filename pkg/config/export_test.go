@@ -0,0 +1,33 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// WithTempConfig writes contents to a temporary config file and points the
+// global viper instance at it, for tests that read or write the config. The
+// returned function resets viper and should be deferred; the file itself is
+// removed with the test's temporary directory.
+func WithTempConfig(t *testing.T, contents string) func() {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(contents), ConfigFilePermissions); err != nil {
+		t.Fatalf("failed to write temporary config file: %v", err)
+	}
+
+	viper.Reset()
+	viper.SetConfigFile(path)
+	viper.SetConfigType("toml")
+
+	if err := viper.ReadInConfig(); err != nil {
+		viper.Reset()
+		t.Fatalf("failed to read temporary config file: %v", err)
+	}
+
+	return viper.Reset
+}
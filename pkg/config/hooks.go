@@ -0,0 +1,19 @@
+package config
+
+// actions reported to OnConfigChange
+const (
+	ConfigChangeWrite  = "write"
+	ConfigChangeDelete = "delete"
+)
+
+// OnConfigChange, if set, is called after a config field has been written to
+// or deleted from the config file. profile is empty for fields that aren't
+// specific to a profile. Values are never passed to the hook so that secrets
+// can't leak into an audit trail.
+var OnConfigChange func(profile, field, action string)
+
+func notifyConfigChange(profile, field, action string) {
+	if OnConfigChange != nil {
+		OnConfigChange(profile, field, action)
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// names of the config fields configuring the HTTP client
+const (
+	HTTPProxyName  = "http_proxy"
+	HTTPSProxyName = "https_proxy"
+	NoProxyName    = "no_proxy"
+	CACertFileName = "ca_cert_file"
+	CACertDirName  = "ca_cert_dir"
+	APITimeoutName = "api_timeout"
+)
+
+// defaultAPITimeout is the timeout of API requests when none is configured,
+// matching the one of the Stripe client libraries
+const defaultAPITimeout = 80 * time.Second
+
+// HTTPClient returns an HTTP client configured from the profile. It uses the
+// proxies set in the http_proxy, https_proxy and no_proxy fields, falling
+// back to the standard environment variables of the same names, and trusts
+// the certificates returned by RootCAs in addition to the system ones.
+// Requests time out after GetAPITimeout. An error is returned when the
+// configured CA certificates can't be loaded, rather than silently trusting
+// the system ones only.
+func (p *Profile) HTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	proxyFunc := p.proxyConfig().ProxyFunc()
+	transport.Proxy = func(r *http.Request) (*url.URL, error) {
+		return proxyFunc(r.URL)
+	}
+
+	rootCAs, err := p.RootCAs()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the configured CA certificates: %w", err)
+	} else if rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    rootCAs,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   p.GetAPITimeout(),
+	}, nil
+}
+
+// GetAPITimeout returns how long commands should wait for API requests to
+// complete: the STRIPE_API_TIMEOUT environment variable first, then the
+// api_timeout field of the profile, both formatted as durations such as 2m.
+// Missing or invalid values fall back to 80 seconds.
+func (p *Profile) GetAPITimeout() time.Duration {
+	for _, value := range []string{
+		os.Getenv("STRIPE_API_TIMEOUT"),
+		viper.GetString(p.GetConfigField(APITimeoutName)),
+	} {
+		if value == "" {
+			continue
+		}
+
+		timeout, err := parseAPITimeout(value)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"prefix": "config.Profile.GetAPITimeout",
+			}).Warnf("Ignoring API timeout: %s", err)
+			continue
+		}
+
+		return timeout
+	}
+
+	return defaultAPITimeout
+}
+
+// parseAPITimeout parses an API timeout, which must be a positive duration.
+func parseAPITimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("%q is not a positive duration such as 80s", value)
+	}
+
+	return timeout, nil
+}
+
+// proxyConfig returns the proxy settings of the profile, with the ones that
+// aren't set in the config file read from the environment.
+func (p *Profile) proxyConfig() *httpproxy.Config {
+	cfg := httpproxy.FromEnvironment()
+
+	for field, value := range map[string]*string{
+		HTTPProxyName:  &cfg.HTTPProxy,
+		HTTPSProxyName: &cfg.HTTPSProxy,
+		NoProxyName:    &cfg.NoProxy,
+	} {
+		if configured := viper.GetString(p.GetConfigField(field)); configured != "" {
+			*value = configured
+		}
+	}
+
+	return cfg
+}
+
+// RootCAs returns the system certificate pool with the certificates found in
+// the ca_cert_file and ca_cert_dir fields of the profile added to it, or nil
+// if neither is set. Every file must contain PEM encoded certificates only.
+func (p *Profile) RootCAs() (*x509.CertPool, error) {
+	paths, err := caCertPaths(
+		viper.GetString(p.GetConfigField(CACertFileName)),
+		viper.GetString(p.GetConfigField(CACertDirName)),
+	)
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	for _, path := range paths {
+		certs, err := readCACerts(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+	}
+
+	return pool, nil
+}
+
+// caCertPaths returns file followed by the regular files in dir, skipping the
+// empty ones.
+func caCertPaths(file, dir string) ([]string, error) {
+	var paths []string
+
+	if file != "" {
+		paths = append(paths, file)
+	}
+
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				paths = append(paths, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// readCACerts parses the PEM encoded certificates in path.
+func readCACerts(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("%s contains a PEM block of type %s, only certificates are supported", path, block.Type)
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s contains an invalid certificate: %w", path, err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 || len(bytes.TrimSpace(data)) > 0 {
+		return nil, fmt.Errorf("%s is not a PEM encoded certificate file", path)
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClientProxy(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	t.Setenv("HTTP_PROXY", "http://env-proxy:3128")
+	t.Setenv("HTTPS_PROXY", "http://env-proxy:3128")
+	t.Setenv("NO_PROXY", "")
+
+	p := Profile{ProfileName: "tests"}
+	viper.Set("tests."+HTTPSProxyName, "http://config-proxy:3128")
+	viper.Set("tests."+NoProxyName, "internal.test")
+
+	client, err := p.HTTPClient()
+	require.NoError(t, err)
+	transport := client.Transport.(*http.Transport)
+
+	for url, want := range map[string]string{
+		"https://api.stripe.com/v1/charges": "http://config-proxy:3128",
+		"http://example.test/":              "http://env-proxy:3128",
+		"https://internal.test/":            "",
+	} {
+		proxyURL, err := transport.Proxy(httptest.NewRequest(http.MethodGet, url, nil))
+		require.NoError(t, err)
+
+		if want == "" {
+			require.Nil(t, proxyURL, url)
+		} else {
+			require.Equal(t, want, proxyURL.String(), url)
+		}
+	}
+}
+
+func TestHTTPClientCACertFile(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	p := Profile{ProfileName: "tests"}
+
+	client, err := p.HTTPClient()
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	require.Error(t, err)
+
+	caCertFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeCertificate(t, caCertFile, server.TLS.Certificates[0])
+	viper.Set("tests."+CACertFileName, caCertFile)
+
+	client, err = p.HTTPClient()
+	require.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// A CA file that can't be loaded is an error
+	viper.Set("tests."+CACertFileName, filepath.Join(t.TempDir(), "missing.pem"))
+
+	_, err = p.HTTPClient()
+	require.Error(t, err)
+}
+
+func writeCertificate(t *testing.T, path string, cert tls.Certificate) {
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0600))
+}
+
+func TestGetAPITimeout(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	t.Setenv("STRIPE_API_TIMEOUT", "")
+
+	p := Profile{ProfileName: "tests"}
+	require.Equal(t, 80*time.Second, p.GetAPITimeout())
+
+	viper.Set("tests."+APITimeoutName, "2m")
+	require.Equal(t, 2*time.Minute, p.GetAPITimeout())
+	client, err := p.HTTPClient()
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, client.Timeout)
+
+	t.Setenv("STRIPE_API_TIMEOUT", "5s")
+	require.Equal(t, 5*time.Second, p.GetAPITimeout())
+
+	// Invalid values are ignored
+	t.Setenv("STRIPE_API_TIMEOUT", "-5s")
+	require.Equal(t, 2*time.Minute, p.GetAPITimeout())
+}
+
+func TestRootCAs(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	p := Profile{ProfileName: "tests"}
+
+	pool, err := p.RootCAs()
+	require.NoError(t, err)
+	require.Nil(t, pool)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeCertificate(t, filepath.Join(dir, "proxy.pem"), server.TLS.Certificates[0])
+	viper.Set("tests."+CACertDirName, dir)
+
+	pool, err = p.RootCAs()
+	require.NoError(t, err)
+	require.NotNil(t, pool)
+
+	client, err := p.HTTPClient()
+	require.NoError(t, err)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a certificate"), 0600))
+
+	_, err = p.RootCAs()
+	require.EqualError(t, err, filepath.Join(dir, "notes.txt")+" is not a PEM encoded certificate file")
+	require.Equal(t, []string{"[tests] ca_cert_dir: " + err.Error()}, p.Validate())
+}
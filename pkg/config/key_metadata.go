@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+// kinds of secret keys
+const (
+	SecretKey     = "secret"
+	RestrictedKey = "restricted"
+)
+
+// KeyMetadata describes an API key based on its prefix only, without any
+// network call
+type KeyMetadata struct {
+	// Prefix is the part of the key identifying its kind and mode, e.g. sk_test
+	Prefix string
+	// Kind is SecretKey or RestrictedKey
+	Kind string
+	// Mode is TestMode or LiveMode
+	Mode string
+}
+
+// ParseKeyMetadata classifies an API key from its prefix.
+func ParseKeyMetadata(key string) (KeyMetadata, error) {
+	err := validators.APIKey(key)
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+
+	parts := strings.Split(key, "_")
+	metadata := KeyMetadata{Prefix: parts[0] + "_" + parts[1]}
+
+	switch parts[0] {
+	case "sk":
+		metadata.Kind = SecretKey
+	case "rk":
+		metadata.Kind = RestrictedKey
+	}
+
+	switch parts[1] {
+	case TestMode:
+		metadata.Mode = TestMode
+	case LiveMode:
+		metadata.Mode = LiveMode
+	default:
+		return KeyMetadata{}, fmt.Errorf("unable to determine the mode of the configured API key")
+	}
+
+	return metadata, nil
+}
+
+// KeyMetadata returns the metadata of the key GetAPIKey returns for the given
+// mode.
+func (p *Profile) KeyMetadata(livemode bool) (KeyMetadata, error) {
+	key, err := p.GetAPIKey(livemode)
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+
+	return ParseKeyMetadata(key)
+}
+
+// IsTestMode returns true if the key GetAPIKey returns for the given mode is a
+// test mode key, and can be used with test-only features such as test clocks.
+func (p *Profile) IsTestMode(livemode bool) bool {
+	metadata, err := p.KeyMetadata(livemode)
+	if err != nil {
+		return false
+	}
+
+	return metadata.Mode == TestMode
+}
@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeyMetadata(t *testing.T) {
+	metadata, err := ParseKeyMetadata("rk_live_1234567890abcd")
+	require.NoError(t, err)
+	require.Equal(t, KeyMetadata{Prefix: "rk_live", Kind: RestrictedKey, Mode: LiveMode}, metadata)
+
+	metadata, err = ParseKeyMetadata("sk_test_1234567890abcd")
+	require.NoError(t, err)
+	require.Equal(t, KeyMetadata{Prefix: "sk_test", Kind: SecretKey, Mode: TestMode}, metadata)
+
+	_, err = ParseKeyMetadata("pk_test_1234567890abcd")
+	require.Error(t, err)
+}
+
+func TestIsTestMode(t *testing.T) {
+	p := Profile{APIKey: "sk_test_1234567890abcd"}
+	require.True(t, p.IsTestMode(false))
+
+	p.APIKey = "sk_live_1234567890abcd"
+	require.False(t, p.IsTestMode(false))
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// KeyState describes whether the key of a mode can be used
+type KeyState string
+
+// The states a key can be in
+const (
+	KeyValid        KeyState = "valid"
+	KeyExpiringSoon KeyState = "expiring_soon"
+	KeyExpired      KeyState = "expired"
+	KeyMissing      KeyState = "missing"
+)
+
+// keyExpiringSoonWindow is how long before its expiration date a key is
+// reported as expiring soon
+const keyExpiringSoonWindow = 7 * 24 * time.Hour
+
+// KeyStatus returns the state of the test mode and live mode keys of the
+// profile, indexed by TestMode and LiveMode. A live mode key that is only
+// redacted in the config file and missing from the keyring is reported as
+// missing. Keys without a readable expiration date are reported as valid.
+func (p *Profile) KeyStatus() map[string]KeyState {
+	_ = viper.ReadInConfig()
+
+	return map[string]KeyState{
+		TestMode: p.keyState(false, firstSet(p.ProfileName, testModeAPIKeyNames...) != ""),
+		LiveMode: p.keyState(true, p.LiveModeAvailable()),
+	}
+}
+
+func (p *Profile) keyState(livemode, available bool) KeyState {
+	if !available {
+		return KeyMissing
+	}
+
+	expiresAt, err := p.GetExpiresAt(livemode)
+	if err != nil {
+		return KeyValid
+	}
+
+	untilExpiry := time.Until(expiresAt)
+
+	switch {
+	case untilExpiry <= 0:
+		return KeyExpired
+	case untilExpiry <= keyExpiringSoonWindow:
+		return KeyExpiringSoon
+	default:
+		return KeyValid
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyStatus(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	date := func(days int) string {
+		return time.Now().AddDate(0, 0, days).UTC().Format(DateStringFormat)
+	}
+
+	profilesFile := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(profilesFile, []byte(`[fresh]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '`+date(60)+`'
+live_mode_api_key = 'sk_live_1234567890abcd'
+live_mode_key_expires_at = '`+date(3)+`'
+
+[stale]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '`+date(-1)+`'
+live_mode_api_key = '`+RedactAPIKey("sk_live_1234567890abcd")+`'
+live_mode_key_expires_at = '`+date(60)+`'
+`), 0600)
+	require.NoError(t, err)
+
+	viper.SetConfigFile(profilesFile)
+
+	fresh := Profile{ProfileName: "fresh"}
+	require.Equal(t, map[string]KeyState{
+		TestMode: KeyValid,
+		LiveMode: KeyExpiringSoon,
+	}, fresh.KeyStatus())
+
+	stale := Profile{ProfileName: "stale"}
+	require.Equal(t, map[string]KeyState{
+		TestMode: KeyExpired,
+		LiveMode: KeyMissing,
+	}, stale.KeyStatus())
+}
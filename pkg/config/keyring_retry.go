@@ -0,0 +1,45 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"github.com/99designs/keyring"
+	log "github.com/sirupsen/logrus"
+)
+
+// keyringAttempts is how many times a keyring operation is tried before
+// giving up on a transient error
+const keyringAttempts = 3
+
+// keyringRetryDelay is how long to wait before the first retry of a keyring
+// operation, doubling after every attempt
+var keyringRetryDelay = 100 * time.Millisecond
+
+// isPermanentKeyringError returns whether err won't go away by trying again:
+// there's no keyring backend at all, or the value simply isn't stored.
+func isPermanentKeyringError(err error) bool {
+	return errors.Is(err, keyring.ErrNoAvailImpl) || errors.Is(err, keyring.ErrKeyNotFound)
+}
+
+// withKeyringRetry calls op until it succeeds, fails with a permanent error or
+// has been tried keyringAttempts times. Locked or busy Secret Service
+// backends, reached through DBus on Linux, fail intermittently and often
+// succeed a moment later.
+func withKeyringRetry(op func() error) error {
+	delay := keyringRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || isPermanentKeyringError(err) || attempt == keyringAttempts {
+			return err
+		}
+
+		log.WithFields(log.Fields{
+			"prefix": "config.withKeyringRetry",
+		}).Debugf("Keyring operation failed, retrying in %s: %v", delay, err)
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/99designs/keyring"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyKeyring fails the first failures operations, like a Secret Service
+// that is still starting
+type flakyKeyring struct {
+	keyring.ArrayKeyring
+
+	failures int
+	calls    int
+}
+
+func (k *flakyKeyring) fail() error {
+	k.calls++
+	if k.calls <= k.failures {
+		return errors.New("dbus: the name org.freedesktop.secrets was not provided")
+	}
+
+	return nil
+}
+
+func (k *flakyKeyring) Get(key string) (keyring.Item, error) {
+	if err := k.fail(); err != nil {
+		return keyring.Item{}, err
+	}
+
+	return k.ArrayKeyring.Get(key)
+}
+
+func (k *flakyKeyring) Set(item keyring.Item) error {
+	if err := k.fail(); err != nil {
+		return err
+	}
+
+	return k.ArrayKeyring.Set(item)
+}
+
+func TestKeyringRetry(t *testing.T) {
+	defer func(delay time.Duration) {
+		KeyRing = nil
+		keyringRetryDelay = delay
+	}(keyringRetryDelay)
+	keyringRetryDelay = time.Millisecond
+
+	p := Profile{ProfileName: "tests"}
+
+	flaky := &flakyKeyring{failures: 2}
+	KeyRing = flaky
+	require.NoError(t, p.storeLivemodeValue(LiveModeAPIKeyName, "sk_live_1234567890", "Live mode API key"))
+	require.Equal(t, 4, flaky.calls)
+
+	flaky.calls = 0
+	value, err := p.RetrieveLivemodeValue(LiveModeAPIKeyName)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_1234567890", value)
+	require.Equal(t, 3, flaky.calls)
+
+	// Give up after keyringAttempts
+	KeyRing = &flakyKeyring{failures: keyringAttempts}
+	_, err = p.RetrieveLivemodeValue(LiveModeAPIKeyName)
+	require.Error(t, err)
+	var keyringErr *KeyringError
+	require.ErrorAs(t, err, &keyringErr)
+}
+
+func TestKeyringRetryPermanentErrors(t *testing.T) {
+	calls := 0
+	err := withKeyringRetry(func() error {
+		calls++
+		return keyring.ErrKeyNotFound
+	})
+	require.ErrorIs(t, err, keyring.ErrKeyNotFound)
+	require.Equal(t, 1, calls)
+
+	calls = 0
+	err = withKeyringRetry(func() error {
+		calls++
+		return keyring.ErrNoAvailImpl
+	})
+	require.ErrorIs(t, err, keyring.ErrNoAvailImpl)
+	require.Equal(t, 1, calls)
+}
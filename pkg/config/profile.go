@@ -1,12 +1,19 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/99designs/keyring"
+	"github.com/BurntSushi/toml"
+	"github.com/imdario/mergo"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
 	"github.com/stripe/stripe-cli/pkg/validators"
@@ -24,22 +31,116 @@ type Profile struct {
 	TerminalPOSDeviceID    string
 	DisplayName            string
 	AccountID              string
+
+	// deviceNameDefault is set when DeviceName wasn't given with --device-name
+	// but defaulted by InitConfig, so that the config file takes precedence
+	deviceNameDefault bool
 }
 
 // config key names
 const (
 	AccountIDName              = "account_id"
+	APIVersionName             = "api_version"
+	ConfirmLiveWritesName      = "confirm_live_writes"
+	DefaultConnectAccountName  = "default_connect_account"
+	DefaultForwardURLName      = "default_forward_url"
+	DefaultModeName            = "default_mode"
 	DeviceNameName             = "device_name"
 	DisplayNameName            = "display_name"
+	GroupName                  = "group"
 	IsTermsAcceptanceValidName = "is_terms_acceptance_valid"
+	LastUsedAtName             = "last_used_at"
+	OutputFormatName           = "output_format"
 	TestModeAPIKeyName         = "test_mode_api_key"
 	TestModePubKeyName         = "test_mode_pub_key"
 	TestModeKeyExpiresAtName   = "test_mode_key_expires_at"
 	LiveModeAPIKeyName         = "live_mode_api_key"
 	LiveModePubKeyName         = "live_mode_pub_key"
 	LiveModeKeyExpiresAtName   = "live_mode_key_expires_at"
+	LiveModeLockedName         = "live_mode_locked"
+)
+
+// names of config fields holding the test mode keys, canonical name first.
+// The other names were used by older versions of the CLI and are still read.
+var (
+	testModeAPIKeyNames = []string{TestModeAPIKeyName, "secret_key", "api_key"}
+
+	// there is a bug with viper.GetStringMapString when the key name is too long, which makes
+	// `config --list --project-name <project_name>` unable to read the project specific config
+	// with test_mode_publishable_key
+	testModePubKeyNames = []string{TestModePubKeyName, "test_mode_publishable_key", "publishable_key"}
+)
+
+// output formats
+const (
+	OutputFormatTable = "table"
+	OutputFormatJSON  = "json"
+)
+
+// sources of configuration values
+const (
+	SourceEnv     = "env"
+	SourceProfile = "profile"
+	SourceConfig  = "config"
+	SourceFlag    = "flag"
+	SourceDefault = "default"
+)
+
+// key modes
+const (
+	TestMode = "test"
+	LiveMode = "live"
 )
 
+// String returns a representation of the profile suitable for logging, in
+// which secret keys are redacted. It has a value receiver so that printing a
+// Profile value doesn't leak secrets either.
+func (p Profile) String() string {
+	return fmt.Sprintf(
+		"Profile{ProfileName: %q, DeviceName: %q, DisplayName: %q, AccountID: %q, APIKey: %q, TestModeAPIKey: %q, TestModePublishableKey: %q, LiveModeAPIKey: %q, LiveModePublishableKey: %q, TerminalPOSDeviceID: %q}",
+		p.ProfileName,
+		p.DeviceName,
+		p.DisplayName,
+		p.AccountID,
+		redactSecret(p.APIKey),
+		redactSecret(p.TestModeAPIKey),
+		p.TestModePublishableKey,
+		redactSecret(p.LiveModeAPIKey),
+		p.LiveModePublishableKey,
+		p.TerminalPOSDeviceID,
+	)
+}
+
+// MergeFrom sets the fields of p that are empty to the value they have in
+// other, so that sources of configuration can be layered from the highest
+// precedence one to the lowest.
+func (p *Profile) MergeFrom(other *Profile) {
+	// Merging two values of the same struct type can't fail
+	_ = mergo.Merge(p, other)
+}
+
+// OverrideFrom sets the fields of p to the value they have in other, when it
+// isn't empty. Unlike MergeFrom, sources are layered from the lowest
+// precedence one to the highest.
+func (p *Profile) OverrideFrom(other *Profile) {
+	_ = mergo.Merge(p, other, mergo.WithOverride)
+}
+
+// Scrub drops the references the profile holds to secret keys, so that they
+// can be garbage collected once the caller is done with them.
+//
+// This is a best effort: Go strings are immutable and can't be overwritten in
+// place, so copies may remain in memory until the garbage collector reclaims
+// them, as well as in viper's copy of the config file and in any string the
+// caller derived from the keys. Values read from the keyring are converted to
+// strings as soon as they are read, so the profile holds no keyring buffer
+// that could be zeroed.
+func (p *Profile) Scrub() {
+	p.APIKey = ""
+	p.TestModeAPIKey = ""
+	p.LiveModeAPIKey = ""
+}
+
 // CreateProfile creates a profile when logging in
 func (p *Profile) CreateProfile() error {
 	writeErr := p.writeProfile(viper.GetViper())
@@ -51,7 +152,8 @@ func (p *Profile) CreateProfile() error {
 }
 
 // GetColor gets the color setting for the user based on the flag or the
-// persisted color stored in the config file
+// persisted color stored in the config file. When neither is set, color is
+// turned off in CI and automatic otherwise.
 func (p *Profile) GetColor() (string, error) {
 	color := viper.GetString("color")
 	if color != "" {
@@ -60,7 +162,12 @@ func (p *Profile) GetColor() (string, error) {
 
 	color = viper.GetString(p.GetConfigField("color"))
 	switch color {
-	case "", ColorAuto:
+	case "":
+		if IsCI() {
+			return ColorOff, nil
+		}
+		return ColorAuto, nil
+	case ColorAuto:
 		return ColorAuto, nil
 	case ColorOn:
 		return ColorOn, nil
@@ -71,21 +178,55 @@ func (p *Profile) GetColor() (string, error) {
 	}
 }
 
-// GetDeviceName returns the configured device name
+// GetOutputFormat returns the output format commands should use when it isn't
+// given on the command line: the STRIPE_OUTPUT_FORMAT environment variable
+// first, then the output_format field of the profile. It defaults to
+// OutputFormatTable, including when the configured value isn't supported.
+func (p *Profile) GetOutputFormat() string {
+	for _, format := range []string{
+		os.Getenv("STRIPE_OUTPUT_FORMAT"),
+		viper.GetString(p.GetConfigField(OutputFormatName)),
+	} {
+		switch format = strings.ToLower(format); format {
+		case OutputFormatTable, OutputFormatJSON:
+			return format
+		}
+	}
+
+	return OutputFormatTable
+}
+
+// GetDeviceName returns the configured device name. Like GetAPIKey, it looks
+// at the STRIPE_DEVICE_NAME environment variable first, then at the profile
+// struct and finally at the config file.
 func (p *Profile) GetDeviceName() (string, error) {
+	deviceName, _, err := p.GetDeviceNameWithSource()
+	return deviceName, err
+}
+
+// GetDeviceNameWithSource returns the configured device name along with where
+// it was found: SourceEnv, SourceProfile, SourceConfig or SourceDefault when
+// it's the default set by InitConfig.
+func (p *Profile) GetDeviceNameWithSource() (string, string, error) {
 	if os.Getenv("STRIPE_DEVICE_NAME") != "" {
-		return os.Getenv("STRIPE_DEVICE_NAME"), nil
+		return os.Getenv("STRIPE_DEVICE_NAME"), SourceEnv, nil
 	}
 
-	if p.DeviceName != "" {
-		return p.DeviceName, nil
+	if p.DeviceName != "" && !p.deviceNameDefault {
+		return p.DeviceName, SourceProfile, nil
 	}
 
 	if err := viper.ReadInConfig(); err == nil {
-		return viper.GetString(p.GetConfigField(DeviceNameName)), nil
+		if deviceName := viper.GetString(p.GetConfigField(DeviceNameName)); deviceName != "" || p.DeviceName == "" {
+			return deviceName, SourceConfig, nil
+		}
 	}
 
-	return "", validators.ErrDeviceNameNotConfigured
+	if p.DeviceName != "" {
+		return p.DeviceName, SourceDefault, nil
+	}
+
+	return "", "", validators.ErrDeviceNameNotConfigured
 }
 
 // GetAccountID returns the accountId for the given profile.
@@ -101,15 +242,21 @@ func (p *Profile) GetAccountID() (string, error) {
 	return "", validators.ErrAccountIDNotConfigured
 }
 
-// GetAPIKey will return the existing key for the given profile
+// GetAPIKey will return the existing key for the given profile. Like
+// GetDeviceName, it looks at the STRIPE_API_KEY environment variable first,
+// then at the profile struct and finally at the config file.
 func (p *Profile) GetAPIKey(livemode bool) (string, error) {
 	envKey := os.Getenv("STRIPE_API_KEY")
 	if envKey != "" {
-		err := validators.APIKey(envKey)
+		mode, err := keyMode(envKey)
 		if err != nil {
 			return "", err
 		}
 
+		if (mode == LiveMode) != livemode {
+			return "", validators.ErrAPIKeyModeMismatch
+		}
+
 		return envKey, nil
 	}
 
@@ -127,30 +274,26 @@ func (p *Profile) GetAPIKey(livemode bool) (string, error) {
 
 	// Try to fetch the API key from the configuration file
 	if !livemode {
-		// If the user doesn't have an api_key field set, they might be using an
-		// old configuration so try to read from secret_key
-		if viper.IsSet(p.GetConfigField("secret_key")) {
-			p.RegisterAlias(TestModeAPIKeyName, "secret_key")
-		} else if viper.IsSet(p.GetConfigField("api_key")) {
-			p.RegisterAlias(TestModeAPIKeyName, "api_key")
-		}
-
+		// If the user doesn't have a test_mode_api_key field set, they might be
+		// using an old configuration so also try to read from secret_key and
+		// api_key. This is resolved on every read rather than with viper
+		// aliases, which are global and would outlive this call.
 		if err := viper.ReadInConfig(); err == nil {
-			key = viper.GetString(p.GetConfigField(TestModeAPIKeyName))
+			key = firstSet(p.ProfileName, testModeAPIKeyNames...)
 		}
 	} else {
 		// p.redactAllLivemodeValues()
-		// key, err = p.retrieveLivemodeValue(LiveModeAPIKeyName)
-		// if err != nil {
-		// 	return "", err
-		// }
 
 		if err := viper.ReadInConfig(); err == nil {
 			key = viper.GetString(p.GetConfigField(LiveModeAPIKeyName))
 		}
 
+		// The config only has a redacted copy of keys stored in the keyring
 		if isRedactedAPIKey(key) {
-			return "", validators.ErrAPIKeyNotConfigured
+			key, err = p.RetrieveLivemodeValue(LiveModeAPIKeyName)
+			if err != nil {
+				return "", err
+			}
 		}
 	}
 
@@ -165,13 +308,207 @@ func (p *Profile) GetAPIKey(livemode bool) (string, error) {
 	return "", validators.ErrAPIKeyNotConfigured
 }
 
+// ActiveKeyMode returns whether the key that would be used for the given
+// profile is a test mode or a live mode key, without exposing the key itself.
+// When both keys are configured, the profile's default_mode wins.
+func (p *Profile) ActiveKeyMode() (string, error) {
+	if envKey := os.Getenv("STRIPE_API_KEY"); envKey != "" {
+		return keyMode(envKey)
+	}
+
+	if p.APIKey != "" {
+		return keyMode(p.APIKey)
+	}
+
+	if err := viper.ReadInConfig(); err == nil {
+		hasTestKey := firstSet(p.ProfileName, testModeAPIKeyNames...) != ""
+		hasLiveKey := viper.GetString(p.GetConfigField(LiveModeAPIKeyName)) != ""
+
+		switch {
+		case hasTestKey && hasLiveKey:
+			return p.GetDefaultMode(), nil
+		case hasTestKey:
+			return TestMode, nil
+		case hasLiveKey:
+			return LiveMode, nil
+		}
+	}
+
+	return "", validators.ErrAPIKeyNotConfigured
+}
+
+// keyMode classifies a key as test or live mode based on its prefix.
+func keyMode(key string) (string, error) {
+	metadata, err := ParseKeyMetadata(key)
+	if err != nil {
+		return "", err
+	}
+
+	return metadata.Mode, nil
+}
+
+// keySwapMu serializes SwapActiveKey calls and their reverts, so that a key
+// and its expiration date are always changed together
+var keySwapMu sync.Mutex
+
+// SwapActiveKey replaces the profile's secret key for the given mode with
+// newKey, with a fresh expiration date, in the config file and in memory. The
+// returned revert function restores the previous key and expiration date,
+// e.g. when the new key fails a verification step. The profile is left
+// untouched if newKey isn't a valid key for the mode or can't be written.
+// Live mode keys are stored in the keyring, which the revert function restores
+// too, unless it's disabled.
+func (p *Profile) SwapActiveKey(livemode bool, newKey string) (func(), error) {
+	newKey = strings.TrimSpace(newKey)
+
+	err := validators.APIKey(newKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyField, expiresAtField, mode := TestModeAPIKeyName, TestModeKeyExpiresAtName, TestMode
+	inMemory := &p.TestModeAPIKey
+	if livemode {
+		keyField, expiresAtField, mode = LiveModeAPIKeyName, LiveModeKeyExpiresAtName, LiveMode
+		inMemory = &p.LiveModeAPIKey
+	}
+
+	if m, err := keyMode(newKey); err != nil || m != mode {
+		return nil, fmt.Errorf("the new key is not a %s mode key", mode)
+	}
+
+	keySwapMu.Lock()
+	defer keySwapMu.Unlock()
+
+	previousKey := viper.GetString(p.GetConfigField(keyField))
+	previousExpiresAt := viper.GetString(p.GetConfigField(expiresAtField))
+	previousInMemory := *inMemory
+
+	// Like when logging in, live mode keys are stored in the keyring with only
+	// a redacted copy in the config file, unless the keyring is disabled
+	useKeyring := livemode && p.KeyringEnabled()
+	configKey := newKey
+
+	var previousItem *keyring.Item
+	if useKeyring {
+		previousItem, err = p.getLivemodeItem(keyField)
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.storeLivemodeValue(keyField, newKey, "Live mode API key")
+		if err != nil {
+			return nil, err
+		}
+
+		configKey = RedactAPIKey(newKey)
+	}
+
+	err = p.setKey(keyField, configKey, expiresAtField, getKeyExpiresAt())
+	if err != nil {
+		if useKeyring {
+			p.restoreLivemodeItem(keyField, previousItem)
+		}
+		return nil, err
+	}
+	*inMemory = newKey
+
+	revert := func() {
+		keySwapMu.Lock()
+		defer keySwapMu.Unlock()
+
+		err := p.setKey(keyField, previousKey, expiresAtField, previousExpiresAt)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"prefix": "config.Profile.SwapActiveKey",
+			}).Errorf("Failed to restore the previous %s: %v", keyField, err)
+			return
+		}
+		if useKeyring {
+			p.restoreLivemodeItem(keyField, previousItem)
+		}
+		*inMemory = previousInMemory
+	}
+
+	return revert, nil
+}
+
+// getLivemodeItem returns the keyring item holding the given livemode value,
+// or nil if it isn't stored.
+func (p *Profile) getLivemodeItem(field string) (*keyring.Item, error) {
+	if KeyRing == nil {
+		return nil, &KeyringError{Err: keyring.ErrNoAvailImpl}
+	}
+
+	var item keyring.Item
+	err := withKeyringRetry(func() (err error) {
+		item, err = KeyRing.Get(p.GetConfigField(field))
+		return err
+	})
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, &KeyringError{Err: err}
+	}
+
+	return &item, nil
+}
+
+// restoreLivemodeItem puts back a keyring item returned by getLivemodeItem,
+// removing the value if there was none. Failures are only logged.
+func (p *Profile) restoreLivemodeItem(field string, item *keyring.Item) {
+	if item == nil {
+		p.deleteLivemodeValues(field)
+		return
+	}
+
+	err := withKeyringRetry(func() error {
+		return KeyRing.Set(*item)
+	})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"prefix": "config.Profile.restoreLivemodeItem",
+		}).Errorf("Failed to restore %s in the OS keyring: %v", p.GetConfigField(field), err)
+	}
+}
+
+// setKey writes a key and its expiration date to the config file in one go,
+// restoring the previous values in memory if the write fails. The file
+// permissions are tightened when a live mode key is written in plain text.
+func (p *Profile) setKey(keyField, key, expiresAtField, expiresAt string) error {
+	previousKey := viper.Get(p.GetConfigField(keyField))
+	previousExpiresAt := viper.Get(p.GetConfigField(expiresAtField))
+
+	viper.Set(p.GetConfigField(keyField), key)
+	viper.Set(p.GetConfigField(expiresAtField), expiresAt)
+
+	err := viper.WriteConfig()
+	if err != nil {
+		viper.Set(p.GetConfigField(keyField), previousKey)
+		viper.Set(p.GetConfigField(expiresAtField), previousExpiresAt)
+		return err
+	}
+
+	if keyField == LiveModeAPIKeyName && key != "" && !isRedactedAPIKey(key) {
+		err = os.Chmod(viper.ConfigFileUsed(), ConfigFilePermissions)
+		if err != nil {
+			return err
+		}
+	}
+
+	notifyConfigChange(p.ProfileName, keyField, ConfigChangeWrite)
+	notifyConfigChange(p.ProfileName, expiresAtField, ConfigChangeWrite)
+
+	return nil
+}
+
 // GetExpiresAt returns the API key expirary date
 func (p *Profile) GetExpiresAt(livemode bool) (time.Time, error) {
 	var timeString string
 	// var err error
 
 	if livemode {
-		// timeString, err = p.retrieveLivemodeValue(LiveModeKeyExpiresAtName)
+		// timeString, err = p.RetrieveLivemodeValue(LiveModeKeyExpiresAtName)
 		// if err != nil {
 		// 	return time.Time{}, err
 		// }
@@ -181,9 +518,9 @@ func (p *Profile) GetExpiresAt(livemode bool) (time.Time, error) {
 	}
 
 	if timeString != "" {
-		expiresAt, err := time.Parse(DateStringFormat, timeString)
-		if err != nil {
-			return time.Time{}, err
+		expiresAt, ok := parseExpiresAt(timeString)
+		if !ok {
+			return time.Time{}, fmt.Errorf("unable to parse key expiration date: %s", timeString)
 		}
 		return expiresAt, nil
 	}
@@ -191,32 +528,43 @@ func (p *Profile) GetExpiresAt(livemode bool) (time.Time, error) {
 	return time.Time{}, validators.ErrAPIKeyNotConfigured
 }
 
-// GetPublishableKey returns the publishable key for the user
-func (p *Profile) GetPublishableKey(livemode bool) (string, error) {
-	var fieldID string
-	var key string
+// ReconcileExpiry replaces the expiry date of the key for the given mode,
+// estimated from KeyValidInDays when the key was stored, with the one
+// provided by the server. The config is left untouched if it already has
+// that date.
+func (p *Profile) ReconcileExpiry(livemode bool, serverExpiry time.Time) error {
+	if serverExpiry.IsZero() {
+		return fmt.Errorf("the server didn't provide a key expiration date")
+	}
 
+	field := TestModeKeyExpiresAtName
 	if livemode {
-		fieldID = LiveModePubKeyName
-	} else {
-		fieldID = TestModePubKeyName
+		field = LiveModeKeyExpiresAtName
+	}
 
-		if viper.IsSet(p.GetConfigField("publishable_key")) {
-			p.RegisterAlias(TestModePubKeyName, "publishable_key")
-		}
-		// there is a bug with viper.GetStringMapString when the key name is too long, which makes
-		// `config --list --project-name <project_name>` unable to read the project specific config
-		if viper.IsSet(p.GetConfigField("test_mode_publishable_key")) {
-			p.RegisterAlias(TestModePubKeyName, "test_mode_publishable_key")
-		}
+	expiresAt := serverExpiry.UTC().Format(DateStringFormat)
+	if viper.GetString(p.GetConfigField(field)) == expiresAt {
+		return nil
 	}
 
+	return p.WriteConfigField(field, expiresAt)
+}
+
+// GetPublishableKey returns the publishable key for the user
+func (p *Profile) GetPublishableKey(livemode bool) (string, error) {
+	var key string
+
 	err := viper.ReadInConfig()
 	if err != nil {
 		return "", err
 	}
 
-	key = viper.GetString(p.GetConfigField(fieldID))
+	if livemode {
+		key = viper.GetString(p.GetConfigField(LiveModePubKeyName))
+	} else {
+		key = firstSet(p.ProfileName, testModePubKeyNames...)
+	}
+
 	if key != "" {
 		return key, nil
 	}
@@ -242,6 +590,176 @@ func (p *Profile) GetTerminalPOSDeviceID() string {
 	return ""
 }
 
+// GetDefaultForwardURL returns the URL webhook events are forwarded to when
+// none is specified, or an empty string if there is none.
+func (p *Profile) GetDefaultForwardURL() string {
+	return viper.GetString(p.GetConfigField(DefaultForwardURLName))
+}
+
+// SetDefaultForwardURL validates and stores the URL webhook events are
+// forwarded to when none is specified.
+func (p *Profile) SetDefaultForwardURL(forwardURL string) error {
+	err := validators.ForwardURL(forwardURL)
+	if err != nil {
+		return err
+	}
+
+	return p.WriteConfigField(DefaultForwardURLName, forwardURL)
+}
+
+// GetGroup returns the group the profile belongs to, or an empty string if it
+// doesn't belong to any.
+func (p *Profile) GetGroup() string {
+	return viper.GetString(p.GetConfigField(GroupName))
+}
+
+// SetGroup adds the profile to the given group, or removes it from its group
+// if group is empty.
+func (p *Profile) SetGroup(group string) error {
+	return p.WriteConfigField(GroupName, strings.TrimSpace(group))
+}
+
+// GetAPIVersion returns the API version pinned for the profile, or an empty
+// string if requests should use the account's default API version.
+func (p *Profile) GetAPIVersion() string {
+	return viper.GetString(p.GetConfigField(APIVersionName))
+}
+
+// SetAPIVersion pins the API version used by the profile's requests, or
+// unpins it if version is empty.
+func (p *Profile) SetAPIVersion(version string) error {
+	return p.WriteConfigField(APIVersionName, strings.TrimSpace(version))
+}
+
+// GetDefaultConnectAccount returns the connected account that the profile's
+// requests are made on behalf of when no account is specified, if any.
+func (p *Profile) GetDefaultConnectAccount() string {
+	return viper.GetString(p.GetConfigField(DefaultConnectAccountName))
+}
+
+// SetDefaultConnectAccount sets the connected account used by default for the
+// profile's requests, or unsets it if account is empty.
+func (p *Profile) SetDefaultConnectAccount(account string) error {
+	return p.WriteConfigField(DefaultConnectAccountName, strings.TrimSpace(account))
+}
+
+// RequiresLiveConfirmation returns whether commands should ask for a
+// confirmation before making changes in live mode with the profile.
+func (p *Profile) RequiresLiveConfirmation() bool {
+	return viper.GetBool(p.GetConfigField(ConfirmLiveWritesName))
+}
+
+// GetLastUsedAt returns the last time the profile was used by a command, as
+// recorded by Touch.
+func (p *Profile) GetLastUsedAt() (time.Time, error) {
+	timeString := viper.GetString(p.GetConfigField(LastUsedAtName))
+	if timeString == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, timeString)
+}
+
+// Touch records the current time as the last time the profile was used. Only
+// the last_used_at line of the config file is updated, the rest of the file
+// is left as is. Nothing is written if the profile isn't in the config file.
+func (p *Profile) Touch() error {
+	profilesFile := viper.ConfigFileUsed()
+
+	contents, err := os.ReadFile(profilesFile)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	updated, ok := setTableField(string(contents), p.ProfileName, LastUsedAtName, `"`+now+`"`)
+	if !ok {
+		return nil
+	}
+
+	// Make sure that nothing but the field changed, in case the file has
+	// constructs that the line based update doesn't handle
+	var before, after map[string]interface{}
+	if _, err := toml.Decode(string(contents), &before); err != nil {
+		return err
+	}
+	if _, err := toml.Decode(updated, &after); err != nil {
+		return fmt.Errorf("failed to update %s: %w", LastUsedAtName, err)
+	}
+
+	table, _ := after[p.ProfileName].(map[string]interface{})
+	if table == nil || table[LastUsedAtName] != now {
+		return fmt.Errorf("failed to update %s", LastUsedAtName)
+	}
+	delete(table, LastUsedAtName)
+	if previous, ok := before[p.ProfileName].(map[string]interface{}); ok {
+		delete(previous, LastUsedAtName)
+	}
+	if !reflect.DeepEqual(before, after) {
+		return fmt.Errorf("failed to update %s", LastUsedAtName)
+	}
+
+	err = os.WriteFile(profilesFile, []byte(updated), ConfigFilePermissions)
+	if err != nil {
+		return err
+	}
+
+	// Reload the file so that later viper writes keep the new value
+	err = viper.ReadInConfig()
+	if err != nil {
+		return err
+	}
+
+	notifyConfigChange(p.ProfileName, LastUsedAtName, ConfigChangeWrite)
+
+	return nil
+}
+
+// setTableField sets field to value, which must be a TOML value, in the given
+// top level table of a TOML document. The field is added right after the
+// table header if it isn't there yet. It returns false if the table isn't
+// found.
+func setTableField(contents, table, field, value string) (string, bool) {
+	lines := strings.Split(contents, "\n")
+	line := field + " = " + value
+
+	for i := range lines {
+		if !isTableHeader(lines[i], table) {
+			continue
+		}
+
+		for j := i + 1; j < len(lines); j++ {
+			trimmed := strings.TrimSpace(lines[j])
+			if strings.HasPrefix(trimmed, "[") {
+				break
+			}
+
+			if key, _, found := strings.Cut(trimmed, "="); found && strings.TrimSpace(key) == field {
+				lines[j] = line
+				return strings.Join(lines, "\n"), true
+			}
+		}
+
+		lines = append(lines[:i+1], append([]string{line}, lines[i+1:]...)...)
+		return strings.Join(lines, "\n"), true
+	}
+
+	return contents, false
+}
+
+// isTableHeader checks whether line is the header of the given table,
+// optionally followed by a comment.
+func isTableHeader(line, table string) bool {
+	rest := strings.TrimPrefix(strings.TrimSpace(line), "["+table+"]")
+	if len(rest) == len(strings.TrimSpace(line)) {
+		return false
+	}
+
+	rest = strings.TrimSpace(rest)
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
+
 // GetConfigField returns the configuration field for the specific profile
 func (p *Profile) GetConfigField(field string) string {
 	return p.ProfileName + "." + field
@@ -253,10 +771,23 @@ func (p *Profile) RegisterAlias(alias, key string) {
 }
 
 // WriteConfigField updates a configuration field and writes the updated
-// configuration to disk.
+// configuration to disk. Values of known fields are validated first.
 func (p *Profile) WriteConfigField(field, value string) error {
+	err := validateField(field, value)
+	if err != nil {
+		return err
+	}
+
 	viper.Set(p.GetConfigField(field), value)
-	return viper.WriteConfig()
+
+	err = viper.WriteConfig()
+	if err != nil {
+		return err
+	}
+
+	notifyConfigChange(p.ProfileName, field, ConfigChangeWrite)
+
+	return nil
 }
 
 // DeleteConfigField deletes a configuration field.
@@ -267,60 +798,107 @@ func (p *Profile) DeleteConfigField(field string) error {
 	}
 
 	// delete livemode redacted values from config and full values from keyring
-	// if field == LiveModeAPIKeyName || field == LiveModePubKeyName || field == LiveModeKeyExpiresAtName {
-	// 	p.deleteLivemodeValue(field)
-	// }
+	for _, livemodeField := range livemodeFields {
+		if field == livemodeField {
+			p.deleteLivemodeValues(field)
+		}
+	}
+
+	// The other fields are only written back as they were
+	_, err = p.writeProfileFields(v)
+	if err != nil {
+		return err
+	}
 
-	return p.writeProfile(v)
+	notifyConfigChange(p.ProfileName, field, ConfigChangeDelete)
+
+	return nil
 }
 
 func (p *Profile) writeProfile(runtimeViper *viper.Viper) error {
+	changedFields, err := p.writeProfileFields(runtimeViper)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range changedFields {
+		notifyConfigChange(p.ProfileName, field, ConfigChangeWrite)
+	}
+
+	return nil
+}
+
+// writeProfileFields writes the fields of the profile along with the rest of
+// runtimeViper to the config file, and returns the names of the fields it set.
+func (p *Profile) writeProfileFields(runtimeViper *viper.Viper) ([]string, error) {
 	profilesFile := viper.ConfigFileUsed()
 
 	err := makePath(profilesFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	var changedFields []string
+
 	if p.DeviceName != "" {
-		runtimeViper.Set(p.GetConfigField(DeviceNameName), strings.TrimSpace(p.DeviceName))
+		deviceName := SanitizeDeviceName(p.DeviceName)
+		if deviceName == "" {
+			return nil, fmt.Errorf("invalid device name %q: it should contain letters or digits", p.DeviceName)
+		}
+
+		runtimeViper.Set(p.GetConfigField(DeviceNameName), deviceName)
+		changedFields = append(changedFields, DeviceNameName)
 	}
 
+	livemodeKeyInConfig := false
 	if p.LiveModeAPIKey != "" {
-		// comment out livemode storage until bugs are ironed out
-		// expiresAt := getKeyExpiresAt()
-
-		// // store redacted key in config
-		// runtimeViper.Set(p.GetConfigField(LiveModeAPIKeyName), RedactAPIKey(strings.TrimSpace(p.LiveModeAPIKey)))
-		// runtimeViper.Set(p.GetConfigField(LiveModeKeyExpiresAtName), expiresAt)
+		liveModeAPIKey := strings.TrimSpace(p.LiveModeAPIKey)
+		configKey := liveModeAPIKey
+
+		if p.KeyringEnabled() {
+			// store actual key in secure keyring and a redacted copy in config,
+			// unless the keyring doesn't work
+			err = p.storeLivemodeValue(LiveModeAPIKeyName, liveModeAPIKey, "Live mode API key")
+			if err == nil {
+				configKey = RedactAPIKey(liveModeAPIKey)
+			} else {
+				log.WithFields(log.Fields{
+					"prefix": "config.Profile.writeProfile",
+				}).Warnf("Storing the live mode API key in the config file: %s", err)
+			}
+		}
 
-		// // store actual key in secure keyring
-		// p.saveLivemodeValue(LiveModeAPIKeyName, strings.TrimSpace(p.LiveModeAPIKey), "Live mode API key")
-		// p.saveLivemodeValue(LiveModeKeyExpiresAtName, expiresAt, "Live mode API key expirary")
+		livemodeKeyInConfig = configKey == liveModeAPIKey
 
-		runtimeViper.Set(p.GetConfigField(LiveModeAPIKeyName), strings.TrimSpace(p.LiveModeAPIKey))
+		runtimeViper.Set(p.GetConfigField(LiveModeAPIKeyName), configKey)
 		runtimeViper.Set(p.GetConfigField(LiveModeKeyExpiresAtName), getKeyExpiresAt())
+		changedFields = append(changedFields, LiveModeAPIKeyName, LiveModeKeyExpiresAtName)
 	}
 
 	if p.LiveModePublishableKey != "" {
 		runtimeViper.Set(p.GetConfigField(LiveModePubKeyName), strings.TrimSpace(p.LiveModePublishableKey))
+		changedFields = append(changedFields, LiveModePubKeyName)
 	}
 
 	if p.TestModeAPIKey != "" {
 		runtimeViper.Set(p.GetConfigField(TestModeAPIKeyName), strings.TrimSpace(p.TestModeAPIKey))
 		runtimeViper.Set(p.GetConfigField(TestModeKeyExpiresAtName), getKeyExpiresAt())
+		changedFields = append(changedFields, TestModeAPIKeyName, TestModeKeyExpiresAtName)
 	}
 
 	if p.TestModePublishableKey != "" {
 		runtimeViper.Set(p.GetConfigField(TestModePubKeyName), strings.TrimSpace(p.TestModePublishableKey))
+		changedFields = append(changedFields, TestModePubKeyName)
 	}
 
 	if p.DisplayName != "" {
 		runtimeViper.Set(p.GetConfigField(DisplayNameName), strings.TrimSpace(p.DisplayName))
+		changedFields = append(changedFields, DisplayNameName)
 	}
 
 	if p.AccountID != "" {
 		runtimeViper.Set(p.GetConfigField(AccountIDName), strings.TrimSpace(p.AccountID))
+		changedFields = append(changedFields, AccountIDName)
 	}
 
 	runtimeViper.MergeInConfig()
@@ -339,13 +917,23 @@ func (p *Profile) writeProfile(runtimeViper *viper.Viper) error {
 
 	// Ensure we preserve the config file type
 	runtimeViper.SetConfigType(filepath.Ext(profilesFile))
+	runtimeViper.SetConfigPermissions(ConfigFilePermissions)
 
 	err = runtimeViper.WriteConfig()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	// When the live mode key is kept in plain text, make sure a config file
+	// created with looser permissions isn't readable by anyone else
+	if livemodeKeyInConfig {
+		err = os.Chmod(profilesFile, ConfigFilePermissions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return changedFields, nil
 }
 
 func (p *Profile) safeRemove(v *viper.Viper, key string) *viper.Viper {
@@ -364,3 +952,27 @@ func (p *Profile) safeRemove(v *viper.Viper, key string) *viper.Viper {
 func getKeyExpiresAt() string {
 	return time.Now().AddDate(0, 0, KeyValidInDays).UTC().Format(DateStringFormat)
 }
+
+// expiresAtFormats are the formats accepted when reading a key expiration
+// date, to cope with hand-edited config files. DateStringFormat is the one
+// we write.
+var expiresAtFormats = []string{
+	DateStringFormat,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006/01/02",
+}
+
+// parseExpiresAt parses a key expiration date in any of expiresAtFormats.
+func parseExpiresAt(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+
+	for _, format := range expiresAtFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -1,9 +1,18 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/99designs/keyring"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/stripe/stripe-cli/pkg/validators"
 )
 
 // DateStringFormat ...
@@ -15,50 +24,231 @@ const KeyValidInDays = 90
 // KeyRing ...
 var KeyRing keyring.Keyring
 
-// saveLivemodeValue saves livemode value of given key in keyring
-// func (p *Profile) saveLivemodeValue(field, value, description string) {
-// 	fieldID := p.GetConfigField(field)
-// 	_ = KeyRing.Set(keyring.Item{
-// 		Key:         fieldID,
-// 		Data:        []byte(value),
-// 		Description: description,
-// 		Label:       fieldID,
-// 	})
-// }
+// ErrKeyringNotPersisted is returned when the keyring reports a value as
+// stored but doesn't return it when read back
+var ErrKeyringNotPersisted = errors.New("the value could not be saved in the OS keyring")
 
-// retrieveLivemodeValue retrieves livemode value of given key in keyring
-// func (p *Profile) retrieveLivemodeValue(key string) (string, error) {
-// 	fieldID := p.GetConfigField(key)
-// 	existingKeys, err := KeyRing.Keys()
-// 	if err != nil {
-// 		return "", err
-// 	}
+// storeLivemodeValue saves livemode value of given key in keyring, and reads
+// it back to check that it was actually stored: some backends report success
+// without persisting anything. Transient keyring errors are retried.
+func (p *Profile) storeLivemodeValue(field, value, description string) error {
+	if KeyRing == nil {
+		return keyring.ErrNoAvailImpl
+	}
 
-// 	for _, item := range existingKeys {
-// 		if item == fieldID {
-// 			value, _ := KeyRing.Get(fieldID)
-// 			return string(value.Data), nil
-// 		}
-// 	}
+	fieldID := p.GetConfigField(field)
+	err := withKeyringRetry(func() error {
+		return KeyRing.Set(keyring.Item{
+			Key:         fieldID,
+			Data:        []byte(value),
+			Description: description,
+			Label:       fieldID,
+		})
+	})
+	if err != nil {
+		return err
+	}
 
-// 	return "", validators.ErrAPIKeyNotConfigured
-// }
+	var item keyring.Item
+	err = withKeyringRetry(func() (err error) {
+		item, err = KeyRing.Get(fieldID)
+		return err
+	})
+	if err != nil || string(item.Data) != value {
+		return ErrKeyringNotPersisted
+	}
 
-// deleteLivemodeValue deletes livemode value of given key in keyring
-// func (p *Profile) deleteLivemodeValue(key string) error {
-// 	fieldID := p.GetConfigField(key)
-// 	existingKeys, err := KeyRing.Keys()
-// 	if err != nil {
-// 		return err
-// 	}
-// 	for _, item := range existingKeys {
-// 		if item == fieldID {
-// 			KeyRing.Remove(fieldID)
-// 			return nil
-// 		}
-// 	}
-// 	return nil
-// }
+	return nil
+}
+
+// UseKeyringName is the config field that can be set to false to keep live
+// mode secrets out of the OS keyring
+const UseKeyringName = "use_keyring"
+
+// KeyringEnabled returns whether live mode secrets are stored in the OS
+// keyring, which is the default. Setting the STRIPE_DISABLE_KEYRING
+// environment variable to true or the use_keyring field to false stores them
+// in the config file instead, which is only readable by the user but isn't
+// encrypted: this is at the user's own risk.
+func (p *Profile) KeyringEnabled() bool {
+	if disabled, err := strconv.ParseBool(os.Getenv("STRIPE_DISABLE_KEYRING")); err == nil && disabled {
+		return false
+	}
+
+	field := p.GetConfigField(UseKeyringName)
+	if viper.IsSet(field) {
+		return viper.GetBool(field)
+	}
+
+	return true
+}
+
+// keyringTestField is the field written by TestKeyring
+const keyringTestField = "keyring_test"
+
+// TestKeyring checks that a value can be stored, read back and removed from
+// the OS keyring, so that callers can suggest another way to store live mode
+// keys before relying on a keyring that doesn't work.
+func (p *Profile) TestKeyring() error {
+	if KeyRing == nil {
+		return &KeyringError{Err: keyring.ErrNoAvailImpl}
+	}
+
+	fieldID := p.GetConfigField(keyringTestField)
+	value := fmt.Sprintf("stripe-cli-%d", time.Now().UnixNano())
+
+	err := KeyRing.Set(keyring.Item{
+		Key:         fieldID,
+		Data:        []byte(value),
+		Description: "Stripe CLI keyring test",
+		Label:       fieldID,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write to the OS keyring: %w", err)
+	}
+
+	item, err := KeyRing.Get(fieldID)
+	if errors.Is(err, keyring.ErrKeyNotFound) || (err == nil && string(item.Data) != value) {
+		// Don't leave whatever may have been stored behind
+		_ = KeyRing.Remove(fieldID)
+		return ErrKeyringNotPersisted
+	} else if err != nil {
+		_ = KeyRing.Remove(fieldID)
+		return &KeyringError{Err: err}
+	}
+
+	err = KeyRing.Remove(fieldID)
+	if err != nil {
+		return fmt.Errorf("unable to remove the test value from the OS keyring: %w", err)
+	}
+
+	return nil
+}
+
+// RetrieveLivemodeValue retrieves livemode value of given key in keyring, or
+// in the config file when the keyring is disabled. It returns
+// validators.ErrAPIKeyNotConfigured if the value isn't stored, and a
+// KeyringError if the keyring itself couldn't be read, after retrying
+// transient errors.
+func (p *Profile) RetrieveLivemodeValue(key string) (string, error) {
+	// Without the keyring, the actual value is in the config file
+	if !p.KeyringEnabled() {
+		value := viper.GetString(p.GetConfigField(key))
+		if value == "" || isRedactedAPIKey(value) {
+			return "", validators.ErrAPIKeyNotConfigured
+		}
+
+		return value, nil
+	}
+
+	if KeyRing == nil {
+		return "", &KeyringError{Err: keyring.ErrNoAvailImpl}
+	}
+
+	var item keyring.Item
+	err := withKeyringRetry(func() (err error) {
+		item, err = KeyRing.Get(p.GetConfigField(key))
+		return err
+	})
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return "", validators.ErrAPIKeyNotConfigured
+	} else if err != nil {
+		return "", &KeyringError{Err: err}
+	}
+
+	return string(item.Data), nil
+}
+
+// LiveModeAvailable reports whether the live mode key of the profile can
+// actually be used on this machine. A config file copied from another machine
+// only has a redacted copy of keys stored in the keyring, so the key must be
+// found in the local keyring too.
+func (p *Profile) LiveModeAvailable() bool {
+	if err := viper.ReadInConfig(); err != nil {
+		return false
+	}
+
+	key := viper.GetString(p.GetConfigField(LiveModeAPIKeyName))
+	if isRedactedAPIKey(key) {
+		var err error
+		key, err = p.RetrieveLivemodeValue(LiveModeAPIKeyName)
+		if err != nil {
+			return false
+		}
+	}
+
+	if key == "" || validators.APIKey(key) != nil {
+		return false
+	}
+
+	mode, err := keyMode(key)
+
+	return err == nil && mode == LiveMode
+}
+
+// LiveKeyNeedsRelogin returns whether the config only has a redacted copy of
+// the live mode key, without the actual key being retrievable from the
+// keyring. This typically happens after copying the config to another machine
+// or reinstalling the OS, and can only be fixed by logging in again.
+func (p *Profile) LiveKeyNeedsRelogin() bool {
+	if err := viper.ReadInConfig(); err != nil {
+		return false
+	}
+
+	if !isRedactedAPIKey(viper.GetString(p.GetConfigField(LiveModeAPIKeyName))) {
+		return false
+	}
+
+	key, err := p.RetrieveLivemodeValue(LiveModeAPIKeyName)
+
+	return err != nil || key == ""
+}
+
+// KeyringError is returned when the OS keyring can't be accessed, as opposed
+// to the requested value not being stored in it
+type KeyringError struct {
+	Err error
+}
+
+func (e *KeyringError) Error() string {
+	return fmt.Sprintf("unable to read from the OS keyring: %s", e.Err)
+}
+
+func (e *KeyringError) Unwrap() error {
+	return e.Err
+}
+
+// livemodeFields are the fields whose actual value may be kept in the keyring
+var livemodeFields = []string{LiveModeAPIKeyName, LiveModePubKeyName, LiveModeKeyExpiresAtName}
+
+// deleteLivemodeValue deletes livemode value of given key in keyring. Values
+// that aren't stored in the keyring are ignored.
+func (p *Profile) deleteLivemodeValue(key string) error {
+	if KeyRing == nil {
+		return nil
+	}
+
+	err := withKeyringRetry(func() error {
+		return KeyRing.Remove(p.GetConfigField(key))
+	})
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// deleteLivemodeValues deletes the given livemode values from the keyring.
+// Failures are only logged, so that the config file can still be cleaned up.
+func (p *Profile) deleteLivemodeValues(keys ...string) {
+	for _, key := range keys {
+		if err := p.deleteLivemodeValue(key); err != nil {
+			log.WithFields(log.Fields{
+				"prefix": "config.Profile.deleteLivemodeValues",
+			}).Warnf("Failed to remove %s from the OS keyring: %s", p.GetConfigField(key), err)
+		}
+	}
+}
 
 // redactAllLivemodeValues redacts all livemode values in the local config file
 // func (p *Profile) redactAllLivemodeValues() {
@@ -79,24 +269,87 @@ var KeyRing keyring.Keyring
 // 	}
 // }
 
-// RedactAPIKey returns a redacted version of API keys. The first 8 and last 4
+// RedactionVisibleCharsName is the top-level config field setting how many
+// trailing characters of redacted keys remain visible
+const RedactionVisibleCharsName = "redaction_visible_chars"
+
+const (
+	// defaultRedactionVisibleChars is the number of trailing characters left
+	// visible when redaction_visible_chars isn't set
+	defaultRedactionVisibleChars = 4
+
+	// maxRedactionVisibleChars is the most trailing characters that can be
+	// left visible, whatever redaction_visible_chars is set to
+	maxRedactionVisibleChars = 8
+)
+
+// redactionVisibleChars returns the number of trailing characters of redacted
+// keys that remain visible, as set by redaction_visible_chars and clamped
+// between 0 and maxRedactionVisibleChars.
+func redactionVisibleChars() int {
+	value := viper.GetString(RedactionVisibleCharsName)
+	if value == "" {
+		return defaultRedactionVisibleChars
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultRedactionVisibleChars
+	}
+
+	if n < 0 {
+		return 0
+	} else if n > maxRedactionVisibleChars {
+		return maxRedactionVisibleChars
+	}
+
+	return n
+}
+
+// RedactAPIKey returns a redacted version of API keys. The first 8
+// characters, which only indicate the type and mode of the key, and the last 4
 // characters are not redacted, everything else is replaced by "*" characters.
+// The number of trailing characters can be changed with the
+// redaction_visible_chars config field, but at least half of the characters
+// after the first 8 are always redacted.
 //
 // It panics if the provided string has less than 12 characters.
 func RedactAPIKey(apiKey string) string {
+	if len(apiKey) < 12 {
+		panic("RedactAPIKey: the API key is too short to be redacted")
+	}
+
+	visible := redactionVisibleChars()
+	if half := (len(apiKey) - 8) / 2; visible > half {
+		visible = half
+	}
+
 	var b strings.Builder
 
-	b.WriteString(apiKey[0:8])                         // #nosec G104 (gosec bug: https://github.com/securego/gosec/issues/267)
-	b.WriteString(strings.Repeat("*", len(apiKey)-12)) // #nosec G104 (gosec bug: https://github.com/securego/gosec/issues/267)
-	b.WriteString(apiKey[len(apiKey)-4:])              // #nosec G104 (gosec bug: https://github.com/securego/gosec/issues/267)
+	b.WriteString(apiKey[0:8])                                // #nosec G104 (gosec bug: https://github.com/securego/gosec/issues/267)
+	b.WriteString(strings.Repeat("*", len(apiKey)-8-visible)) // #nosec G104 (gosec bug: https://github.com/securego/gosec/issues/267)
+	b.WriteString(apiKey[len(apiKey)-visible:])               // #nosec G104 (gosec bug: https://github.com/securego/gosec/issues/267)
 
 	return b.String()
 }
 
-// isRedactedAPIKey checks if the input string is a refacted api key
+// redactSecret is like RedactAPIKey, but fully redacts values that are too
+// short to be partially shown instead of panicking.
+func redactSecret(value string) string {
+	if len(value) < 12 {
+		return strings.Repeat("*", len(value))
+	}
+
+	return RedactAPIKey(value)
+}
+
+// isRedactedAPIKey checks if the input string is a refacted api key. Keys
+// redacted with any number of visible trailing characters are recognized, so
+// that changing redaction_visible_chars doesn't make previously redacted keys
+// look like actual keys.
 func isRedactedAPIKey(apiKey string) bool {
 	keyParts := strings.Split(apiKey, "_")
-	if len(keyParts) < 3 {
+	if len(keyParts) < 3 || len(apiKey) < 12 {
 		return false
 	}
 
@@ -104,9 +357,13 @@ func isRedactedAPIKey(apiKey string) bool {
 		return false
 	}
 
-	if RedactAPIKey(apiKey) != apiKey {
+	secret := apiKey[8:]
+	trailing := strings.TrimLeft(secret, "*")
+
+	// At least half of the secret part is always redacted
+	if len(secret)-len(trailing) < (len(secret)+1)/2 {
 		return false
 	}
 
-	return true
+	return !strings.Contains(trailing, "*")
 }
@@ -0,0 +1,406 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/99designs/keyring"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+// lyingKeyring reports every write as successful without storing anything
+type lyingKeyring struct {
+	keyring.ArrayKeyring
+}
+
+func (k *lyingKeyring) Set(item keyring.Item) error {
+	return nil
+}
+
+func TestStoreLivemodeValue(t *testing.T) {
+	defer func() { KeyRing = nil }()
+	p := Profile{ProfileName: "tests"}
+
+	KeyRing = keyring.NewArrayKeyring(nil)
+	err := p.storeLivemodeValue(LiveModeAPIKeyName, "sk_live_1234567890", "Live mode API key")
+	require.NoError(t, err)
+
+	item, err := KeyRing.Get("tests." + LiveModeAPIKeyName)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_1234567890", string(item.Data))
+}
+
+func TestStoreLivemodeValueNotPersisted(t *testing.T) {
+	defer func() { KeyRing = nil }()
+	p := Profile{ProfileName: "tests"}
+
+	KeyRing = &lyingKeyring{}
+	err := p.storeLivemodeValue(LiveModeAPIKeyName, "sk_live_1234567890", "Live mode API key")
+	require.ErrorIs(t, err, ErrKeyringNotPersisted)
+}
+
+// brokenKeyring fails every operation, like an unreachable Secret Service
+type brokenKeyring struct {
+	keyring.ArrayKeyring
+}
+
+func (k *brokenKeyring) Get(key string) (keyring.Item, error) {
+	return keyring.Item{}, errors.New("dbus: connection refused")
+}
+
+func TestGetAPIKeyFromKeyring(t *testing.T) {
+	defer func() { KeyRing = nil }()
+
+	profilesFile := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(profilesFile, []byte("[tests]\nlive_mode_api_key = '"+RedactAPIKey("sk_live_1234567890abcd")+"'\n"), 0600)
+	require.NoError(t, err)
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigFile(profilesFile)
+
+	p := Profile{ProfileName: "tests"}
+
+	KeyRing = keyring.NewArrayKeyring(nil)
+	_, err = p.GetAPIKey(true)
+	require.ErrorIs(t, err, validators.ErrAPIKeyNotConfigured)
+
+	KeyRing = &brokenKeyring{}
+	_, err = p.GetAPIKey(true)
+	var keyringErr *KeyringError
+	require.ErrorAs(t, err, &keyringErr)
+
+	KeyRing = keyring.NewArrayKeyring([]keyring.Item{{Key: "tests." + LiveModeAPIKeyName, Data: []byte("sk_live_1234567890abcd")}})
+	key, err := p.GetAPIKey(true)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_1234567890abcd", key)
+}
+
+// corruptingKeyring stores different data than it was given
+type corruptingKeyring struct {
+	keyring.ArrayKeyring
+}
+
+func (k *corruptingKeyring) Set(item keyring.Item) error {
+	item.Data = append(item.Data, '!')
+	return k.ArrayKeyring.Set(item)
+}
+
+func TestTestKeyring(t *testing.T) {
+	defer func() { KeyRing = nil }()
+	p := Profile{ProfileName: "tests"}
+
+	KeyRing = keyring.NewArrayKeyring(nil)
+	require.NoError(t, p.TestKeyring())
+
+	keys, err := KeyRing.Keys()
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	KeyRing = &lyingKeyring{}
+	require.ErrorIs(t, p.TestKeyring(), ErrKeyringNotPersisted)
+
+	// The test value is removed even when it doesn't match
+	KeyRing = &corruptingKeyring{}
+	require.ErrorIs(t, p.TestKeyring(), ErrKeyringNotPersisted)
+
+	keys, err = KeyRing.Keys()
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	KeyRing = &brokenKeyring{}
+	var keyringErr *KeyringError
+	require.ErrorAs(t, p.TestKeyring(), &keyringErr)
+
+	KeyRing = nil
+	require.ErrorIs(t, p.TestKeyring(), keyring.ErrNoAvailImpl)
+}
+
+func TestLiveModeAvailable(t *testing.T) {
+	defer func() { KeyRing = nil }()
+
+	profilesFile := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(profilesFile, []byte(`[copied]
+live_mode_api_key = '`+RedactAPIKey("sk_live_1234567890abcd")+`'
+
+[plain]
+live_mode_api_key = 'sk_live_1234567890abcd'
+
+[wrong]
+live_mode_api_key = 'sk_test_1234567890abcd'
+
+[none]
+test_mode_api_key = 'sk_test_1234567890abcd'
+`), 0600)
+	require.NoError(t, err)
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigFile(profilesFile)
+
+	copied := Profile{ProfileName: "copied"}
+
+	KeyRing = keyring.NewArrayKeyring(nil)
+	require.False(t, copied.LiveModeAvailable())
+
+	KeyRing = &brokenKeyring{}
+	require.False(t, copied.LiveModeAvailable())
+
+	KeyRing = keyring.NewArrayKeyring([]keyring.Item{{Key: "copied." + LiveModeAPIKeyName, Data: []byte("sk_live_1234567890abcd")}})
+	require.True(t, copied.LiveModeAvailable())
+
+	require.True(t, (&Profile{ProfileName: "plain"}).LiveModeAvailable())
+	require.False(t, (&Profile{ProfileName: "wrong"}).LiveModeAvailable())
+	require.False(t, (&Profile{ProfileName: "none"}).LiveModeAvailable())
+}
+
+func TestKeyringDisabled(t *testing.T) {
+	defer func() { KeyRing = nil }()
+
+	profilesFile := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(profilesFile, []byte(`[plain]
+use_keyring = false
+live_mode_api_key = 'sk_live_1234567890abcd'
+
+[redacted]
+use_keyring = false
+live_mode_api_key = '`+RedactAPIKey("sk_live_1234567890abcd")+`'
+`), 0644)
+	require.NoError(t, err)
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigFile(profilesFile)
+	require.NoError(t, viper.ReadInConfig())
+
+	// The keyring must not be consulted at all
+	KeyRing = &brokenKeyring{}
+
+	plain := Profile{ProfileName: "plain"}
+	require.False(t, plain.KeyringEnabled())
+
+	key, err := plain.GetAPIKey(true)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_1234567890abcd", key)
+
+	key, err = plain.RetrieveLivemodeValue(LiveModeAPIKeyName)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_1234567890abcd", key)
+
+	redacted := Profile{ProfileName: "redacted"}
+	_, err = redacted.GetAPIKey(true)
+	require.ErrorIs(t, err, validators.ErrAPIKeyNotConfigured)
+
+	// Live mode keys written without the keyring tighten the file permissions
+	plain.LiveModeAPIKey = "sk_live_0987654321abcd"
+	require.NoError(t, plain.writeProfile(viper.New()))
+
+	info, err := os.Stat(profilesFile)
+	require.NoError(t, err)
+	require.Equal(t, ConfigFilePermissions, info.Mode().Perm())
+}
+
+func TestWriteProfileLiveKeyPermissions(t *testing.T) {
+	defer func() { KeyRing = nil }()
+	defer WithTempConfig(t, "[tests]\ndevice_name = 'st-testing'\n")()
+	require.NoError(t, os.Chmod(viper.ConfigFileUsed(), 0644))
+
+	// The keyring doesn't keep the key, so it falls back to the config file
+	KeyRing = &lyingKeyring{}
+
+	p := Profile{ProfileName: "tests", LiveModeAPIKey: "sk_live_0987654321abcd"}
+	require.True(t, p.KeyringEnabled())
+	require.NoError(t, p.writeProfile(viper.New()))
+
+	info, err := os.Stat(viper.ConfigFileUsed())
+	require.NoError(t, err)
+	require.Equal(t, ConfigFilePermissions, info.Mode().Perm())
+
+	require.NoError(t, viper.ReadInConfig())
+	require.Equal(t, "sk_live_0987654321abcd", viper.GetString("tests."+LiveModeAPIKeyName))
+}
+
+func TestWriteProfileLiveKeyInKeyring(t *testing.T) {
+	defer func() { KeyRing = nil }()
+	defer WithTempConfig(t, "[tests]\ndevice_name = 'st-testing'\n")()
+
+	KeyRing = keyring.NewArrayKeyring(nil)
+
+	p := Profile{ProfileName: "tests", LiveModeAPIKey: "sk_live_0987654321abcd"}
+	require.NoError(t, p.writeProfile(viper.New()))
+
+	require.NoError(t, viper.ReadInConfig())
+	require.Equal(t, RedactAPIKey("sk_live_0987654321abcd"), viper.GetString("tests."+LiveModeAPIKeyName))
+	require.NotEmpty(t, viper.GetString("tests."+LiveModeKeyExpiresAtName))
+
+	key, err := (&Profile{ProfileName: "tests"}).GetAPIKey(true)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_0987654321abcd", key)
+}
+
+func TestKeyringEnabled(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	p := Profile{ProfileName: "tests"}
+	require.True(t, p.KeyringEnabled())
+
+	viper.Set("tests."+UseKeyringName, true)
+	require.True(t, p.KeyringEnabled())
+
+	t.Setenv("STRIPE_DISABLE_KEYRING", "true")
+	require.False(t, p.KeyringEnabled())
+
+	t.Setenv("STRIPE_DISABLE_KEYRING", "false")
+	viper.Set("tests."+UseKeyringName, false)
+	require.False(t, p.KeyringEnabled())
+}
+
+func TestLiveKeyNeedsRelogin(t *testing.T) {
+	defer func() { KeyRing = nil }()
+	defer WithTempConfig(t, `[copied]
+live_mode_api_key = '`+RedactAPIKey("sk_live_1234567890abcd")+`'
+
+[plain]
+live_mode_api_key = 'sk_live_1234567890abcd'
+
+[none]
+test_mode_api_key = 'sk_test_1234567890abcd'
+`)()
+
+	copied := Profile{ProfileName: "copied"}
+
+	KeyRing = keyring.NewArrayKeyring(nil)
+	require.True(t, copied.LiveKeyNeedsRelogin())
+
+	KeyRing = &brokenKeyring{}
+	require.True(t, copied.LiveKeyNeedsRelogin())
+
+	KeyRing = keyring.NewArrayKeyring([]keyring.Item{{Key: "copied." + LiveModeAPIKeyName, Data: []byte("sk_live_1234567890abcd")}})
+	require.False(t, copied.LiveKeyNeedsRelogin())
+
+	require.False(t, (&Profile{ProfileName: "plain"}).LiveKeyNeedsRelogin())
+	require.False(t, (&Profile{ProfileName: "none"}).LiveKeyNeedsRelogin())
+}
+
+func TestRedactAPIKeyVisibleChars(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	key := "sk_live_1234567890abcdefghij"
+	require.Equal(t, "sk_live_****************ghij", RedactAPIKey(key))
+
+	viper.Set(RedactionVisibleCharsName, 6)
+	require.Equal(t, "sk_live_**************efghij", RedactAPIKey(key))
+
+	viper.Set(RedactionVisibleCharsName, 0)
+	require.Equal(t, "sk_live_********************", RedactAPIKey(key))
+
+	// Never reveal more than the maximum, nor more than half the secret
+	viper.Set(RedactionVisibleCharsName, 100)
+	require.Equal(t, "sk_live_************cdefghij", RedactAPIKey(key))
+	require.Equal(t, "sk_live_*****abcde", RedactAPIKey("sk_live_12345abcde"))
+
+	viper.Set(RedactionVisibleCharsName, "lots")
+	require.Equal(t, "sk_live_****************ghij", RedactAPIKey(key))
+}
+
+func TestIsRedactedAPIKey(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	redacted := RedactAPIKey("sk_live_1234567890abcdefghij")
+	require.True(t, isRedactedAPIKey(redacted))
+
+	// Keys redacted with another setting are still recognized
+	viper.Set(RedactionVisibleCharsName, 8)
+	require.True(t, isRedactedAPIKey(redacted))
+	require.True(t, isRedactedAPIKey(RedactAPIKey("sk_live_1234567890abcdefghij")))
+
+	require.False(t, isRedactedAPIKey("sk_live_1234567890abcdefghij"))
+	require.False(t, isRedactedAPIKey("sk_live_1234567890abcdef**ij"))
+	require.False(t, isRedactedAPIKey("pk_live_********************"))
+}
+
+func TestLivemodeValuesRemovedFromKeyring(t *testing.T) {
+	defer func() { KeyRing = nil }()
+	defer WithTempConfig(t, `[a]
+live_mode_api_key = '`+RedactAPIKey("sk_live_1234567890abcd")+`'
+
+[b]
+live_mode_api_key = '`+RedactAPIKey("sk_live_1234567890abcd")+`'
+
+[c]
+live_mode_api_key = '`+RedactAPIKey("sk_live_1234567890abcd")+`'
+`)()
+
+	KeyRing = keyring.NewArrayKeyring([]keyring.Item{
+		{Key: "a." + LiveModeAPIKeyName, Data: []byte("sk_live_1234567890abcd")},
+		{Key: "b." + LiveModeAPIKeyName, Data: []byte("sk_live_1234567890abcd")},
+		{Key: "c." + LiveModeAPIKeyName, Data: []byte("sk_live_1234567890abcd")},
+	})
+
+	inKeyring := func(profile string) bool {
+		_, err := KeyRing.Get(profile + "." + LiveModeAPIKeyName)
+		return err == nil
+	}
+
+	require.NoError(t, (&Profile{ProfileName: "a"}).DeleteConfigField(LiveModeAPIKeyName))
+	require.False(t, inKeyring("a"))
+	require.True(t, inKeyring("b"))
+
+	c := &Config{}
+	require.NoError(t, c.RemoveProfile("b"))
+	require.False(t, inKeyring("b"))
+	require.True(t, inKeyring("c"))
+
+	require.NoError(t, c.RemoveAllProfiles())
+	require.False(t, inKeyring("c"))
+}
+
+func TestSwapActiveLiveKey(t *testing.T) {
+	defer func() { KeyRing = nil }()
+	defer WithTempConfig(t, `[tests]
+live_mode_api_key = '`+RedactAPIKey("sk_live_old4567890abcd")+`'
+live_mode_key_expires_at = '2022-09-01'
+`)()
+
+	KeyRing = keyring.NewArrayKeyring([]keyring.Item{{Key: "tests." + LiveModeAPIKeyName, Data: []byte("sk_live_old4567890abcd")}})
+
+	requireNotInConfig := func(key string) {
+		contents, err := os.ReadFile(viper.ConfigFileUsed())
+		require.NoError(t, err)
+		require.NotContains(t, string(contents), key)
+	}
+
+	p := Profile{ProfileName: "tests"}
+
+	revert, err := p.SwapActiveKey(true, "sk_live_new4567890abcd")
+	require.NoError(t, err)
+	requireNotInConfig("sk_live_new4567890abcd")
+	require.Equal(t, RedactAPIKey("sk_live_new4567890abcd"), viper.GetString("tests."+LiveModeAPIKeyName))
+
+	item, err := KeyRing.Get("tests." + LiveModeAPIKeyName)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_new4567890abcd", string(item.Data))
+
+	revert()
+	requireNotInConfig("sk_live_old4567890abcd")
+	require.Equal(t, RedactAPIKey("sk_live_old4567890abcd"), viper.GetString("tests."+LiveModeAPIKeyName))
+
+	p.LiveModeAPIKey = ""
+	key, err := p.GetAPIKey(true)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_old4567890abcd", key)
+
+	// A keyring that doesn't keep the key leaves the profile untouched
+	KeyRing = &lyingKeyring{}
+	_, err = p.SwapActiveKey(true, "sk_live_new4567890abcd")
+	require.ErrorIs(t, err, ErrKeyringNotPersisted)
+	requireNotInConfig("sk_live_new4567890abcd")
+}
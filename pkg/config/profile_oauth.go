@@ -0,0 +1,74 @@
+package config
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/99designs/keyring"
+)
+
+// OAuthTokenName is the name of the keyring item holding a profile's OAuth tokens
+const OAuthTokenName = "oauth_token"
+
+// oauthRefreshMargin is how long before its expiry an access token is
+// considered in need of a refresh, so that it doesn't expire mid-request.
+const oauthRefreshMargin = 5 * time.Minute
+
+// OAuthToken holds the tokens obtained through the browser based login flow
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// RefreshNeeded returns true when the access token is missing, expired or
+// about to expire.
+func (t *OAuthToken) RefreshNeeded() bool {
+	if t == nil || t.AccessToken == "" {
+		return true
+	}
+
+	return time.Now().Add(oauthRefreshMargin).After(t.ExpiresAt)
+}
+
+// SetOAuthToken stores the OAuth tokens of the profile in the keyring
+func (p *Profile) SetOAuthToken(token *OAuthToken) error {
+	if KeyRing == nil {
+		return keyring.ErrNoAvailImpl
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	fieldID := p.GetConfigField(OAuthTokenName)
+
+	return KeyRing.Set(keyring.Item{
+		Key:         fieldID,
+		Data:        data,
+		Description: "OAuth tokens",
+		Label:       fieldID,
+	})
+}
+
+// GetOAuthToken returns the OAuth tokens of the profile stored in the keyring.
+// It returns keyring.ErrKeyNotFound if the profile has no tokens.
+func (p *Profile) GetOAuthToken() (*OAuthToken, error) {
+	if KeyRing == nil {
+		return nil, keyring.ErrNoAvailImpl
+	}
+
+	item, err := KeyRing.Get(p.GetConfigField(OAuthTokenName))
+	if err != nil {
+		return nil, err
+	}
+
+	var token OAuthToken
+	err = json.Unmarshal(item.Data, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/99designs/keyring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthTokenRoundTrip(t *testing.T) {
+	KeyRing = keyring.NewArrayKeyring(nil)
+	defer func() { KeyRing = nil }()
+
+	p := Profile{ProfileName: "tests"}
+
+	_, err := p.GetOAuthToken()
+	require.ErrorIs(t, err, keyring.ErrKeyNotFound)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	err = p.SetOAuthToken(&OAuthToken{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		ExpiresAt:    expiresAt,
+	})
+	require.NoError(t, err)
+
+	token, err := p.GetOAuthToken()
+	require.NoError(t, err)
+	require.Equal(t, "access", token.AccessToken)
+	require.Equal(t, "refresh", token.RefreshToken)
+	require.True(t, expiresAt.Equal(token.ExpiresAt))
+	require.False(t, token.RefreshNeeded())
+
+	token.ExpiresAt = time.Now().Add(time.Minute)
+	require.True(t, token.RefreshNeeded())
+}
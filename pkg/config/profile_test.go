@@ -6,9 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/validators"
 )
 
 func TestWriteProfile(t *testing.T) {
@@ -101,6 +104,473 @@ test_mode_key_expires_at = '` + expiresAt + `'
 	cleanUp(c.ProfilesFile)
 }
 
+func TestActiveKeyMode(t *testing.T) {
+	p := Profile{
+		ProfileName: "tests",
+		APIKey:      "sk_live_1234567890",
+	}
+
+	mode, err := p.ActiveKeyMode()
+	require.NoError(t, err)
+	require.Equal(t, LiveMode, mode)
+
+	t.Setenv("STRIPE_API_KEY", "rk_test_1234567890")
+
+	mode, err = p.ActiveKeyMode()
+	require.NoError(t, err)
+	require.Equal(t, TestMode, mode)
+}
+
+func TestActiveKeyModeDefaultMode(t *testing.T) {
+	defer WithTempConfig(t, `[tests]
+test_mode_api_key = "sk_test_1234567890"
+live_mode_api_key = "sk_live_****567890"
+`)()
+
+	p := Profile{ProfileName: "tests"}
+
+	mode, err := p.ActiveKeyMode()
+	require.NoError(t, err)
+	require.Equal(t, TestMode, mode)
+
+	viper.Set(p.GetConfigField(DefaultModeName), LiveMode)
+
+	mode, err = p.ActiveKeyMode()
+	require.NoError(t, err)
+	require.Equal(t, LiveMode, mode)
+}
+
+func TestGetAPIKeyEnvModeMismatch(t *testing.T) {
+	p := Profile{ProfileName: "tests"}
+
+	t.Setenv("STRIPE_API_KEY", "sk_live_1234567890")
+
+	_, err := p.GetAPIKey(false)
+	require.ErrorIs(t, err, validators.ErrAPIKeyModeMismatch)
+
+	key, err := p.GetAPIKey(true)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_1234567890", key)
+}
+
+func TestOnConfigChange(t *testing.T) {
+	profilesFile := filepath.Join(os.TempDir(), "stripe", "config.toml")
+	p := Profile{
+		DeviceName:     "st-testing",
+		ProfileName:    "tests",
+		TestModeAPIKey: "sk_test_123",
+	}
+
+	c := &Config{
+		Color:        "auto",
+		LogLevel:     "info",
+		Profile:      p,
+		ProfilesFile: profilesFile,
+	}
+	c.InitConfig()
+
+	var changes []string
+	OnConfigChange = func(profile, field, action string) {
+		changes = append(changes, profile+"."+field+":"+action)
+	}
+	defer func() { OnConfigChange = nil }()
+
+	err := p.writeProfile(viper.New())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"tests.device_name:write",
+		"tests.test_mode_api_key:write",
+		"tests.test_mode_key_expires_at:write",
+	}, changes)
+
+	// Deleting a field only reports the deletion
+	changes = nil
+	viper.SetConfigFile(profilesFile)
+	require.NoError(t, viper.ReadInConfig())
+	require.NoError(t, p.DeleteConfigField(TestModeKeyExpiresAtName))
+	require.Equal(t, []string{"tests.test_mode_key_expires_at:delete"}, changes)
+
+	cleanUp(c.ProfilesFile)
+}
+
+func TestProfileStringRedactsSecrets(t *testing.T) {
+	p := Profile{
+		ProfileName:    "tests",
+		DeviceName:     "st-testing",
+		APIKey:         "sk_test_abcdefghijklmnop",
+		TestModeAPIKey: "rk_test_qrstuvwxyz123456",
+		LiveModeAPIKey: "sk_live_7890abcdefghijkl",
+	}
+
+	for _, output := range []string{p.String(), fmt.Sprintf("%v", p), fmt.Sprintf("%+v", &p)} {
+		require.Contains(t, output, "st-testing")
+		require.NotContains(t, output, p.APIKey)
+		require.NotContains(t, output, p.TestModeAPIKey)
+		require.NotContains(t, output, p.LiveModeAPIKey)
+		require.Contains(t, output, "sk_live_************ijkl")
+	}
+}
+
+func TestGetOutputFormat(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	p := Profile{ProfileName: "tests"}
+	require.Equal(t, OutputFormatTable, p.GetOutputFormat())
+
+	viper.Set("tests."+OutputFormatName, "JSON")
+	require.Equal(t, OutputFormatJSON, p.GetOutputFormat())
+
+	t.Setenv("STRIPE_OUTPUT_FORMAT", "table")
+	require.Equal(t, OutputFormatTable, p.GetOutputFormat())
+
+	// Unsupported values are ignored
+	t.Setenv("STRIPE_OUTPUT_FORMAT", "yaml")
+	require.Equal(t, OutputFormatJSON, p.GetOutputFormat())
+}
+
+func TestRequiresLiveConfirmation(t *testing.T) {
+	defer WithTempConfig(t, `[careful]
+confirm_live_writes = true
+
+[reckless]
+confirm_live_writes = false
+`)()
+
+	require.True(t, (&Profile{ProfileName: "careful"}).RequiresLiveConfirmation())
+	require.False(t, (&Profile{ProfileName: "reckless"}).RequiresLiveConfirmation())
+	require.False(t, (&Profile{ProfileName: "unset"}).RequiresLiveConfirmation())
+}
+
+func TestMergeFrom(t *testing.T) {
+	p := Profile{ProfileName: "tests", DeviceName: "from-flags"}
+	p.MergeFrom(&Profile{ProfileName: "other", DeviceName: "from-env", APIKey: "sk_test_1234567890abcd"})
+
+	require.Equal(t, Profile{ProfileName: "tests", DeviceName: "from-flags", APIKey: "sk_test_1234567890abcd"}, p)
+
+	p.OverrideFrom(&Profile{DeviceName: "from-override"})
+	require.Equal(t, Profile{ProfileName: "tests", DeviceName: "from-override", APIKey: "sk_test_1234567890abcd"}, p)
+}
+
+func TestScrub(t *testing.T) {
+	p := Profile{
+		ProfileName:            "tests",
+		APIKey:                 "sk_test_abcdefghijklmnop",
+		TestModeAPIKey:         "rk_test_qrstuvwxyz123456",
+		TestModePublishableKey: "pk_test_abcdefghijklmnop",
+		LiveModeAPIKey:         "sk_live_7890abcdefghijkl",
+	}
+
+	p.Scrub()
+
+	require.Empty(t, p.APIKey)
+	require.Empty(t, p.TestModeAPIKey)
+	require.Empty(t, p.LiveModeAPIKey)
+	require.Equal(t, "tests", p.ProfileName)
+	require.Equal(t, "pk_test_abcdefghijklmnop", p.TestModePublishableKey)
+}
+
+func TestParseExpiresAt(t *testing.T) {
+	expected := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, value := range []string{"2022-09-01", " 2022-09-01 ", "2022-09-01T00:00:00Z", "2022-09-01 00:00:00", "2022/09/01"} {
+		expiresAt, ok := parseExpiresAt(value)
+		require.True(t, ok, value)
+		require.True(t, expected.Equal(expiresAt), value)
+	}
+
+	_, ok := parseExpiresAt("next tuesday")
+	require.False(t, ok)
+}
+
+func TestGetDeviceNameWithSource(t *testing.T) {
+	p := Profile{DeviceName: "st-testing"}
+
+	name, source, err := p.GetDeviceNameWithSource()
+	require.NoError(t, err)
+	require.Equal(t, "st-testing", name)
+	require.Equal(t, SourceProfile, source)
+
+	t.Setenv("STRIPE_DEVICE_NAME", "from-env")
+
+	name, source, err = p.GetDeviceNameWithSource()
+	require.NoError(t, err)
+	require.Equal(t, "from-env", name)
+	require.Equal(t, SourceEnv, source)
+}
+
+func TestGetDeviceNameWithSourceDefault(t *testing.T) {
+	defer WithTempConfig(t, "[tests]\ndevice_name = 'from-config'\n")()
+
+	// Like InitConfig when --device-name isn't given
+	p := Profile{ProfileName: "tests", DeviceName: "hostname", deviceNameDefault: true}
+
+	name, source, err := p.GetDeviceNameWithSource()
+	require.NoError(t, err)
+	require.Equal(t, "from-config", name)
+	require.Equal(t, SourceConfig, source)
+
+	p.ProfileName = "other"
+	name, source, err = p.GetDeviceNameWithSource()
+	require.NoError(t, err)
+	require.Equal(t, "hostname", name)
+	require.Equal(t, SourceDefault, source)
+}
+
+func TestLegacyKeysDoNotLeakAcrossProfiles(t *testing.T) {
+	defer WithTempConfig(t, `[legacy]
+secret_key = 'sk_test_legacy7890abcd'
+publishable_key = 'pk_test_legacy7890abcd'
+
+[current]
+test_mode_api_key = 'sk_test_current890abcd'
+test_mode_pub_key = 'pk_test_current890abcd'
+`)()
+
+	legacy := Profile{ProfileName: "legacy"}
+	current := Profile{ProfileName: "current"}
+
+	key, err := legacy.GetAPIKey(false)
+	require.NoError(t, err)
+	require.Equal(t, "sk_test_legacy7890abcd", key)
+
+	pubKey, err := legacy.GetPublishableKey(false)
+	require.NoError(t, err)
+	require.Equal(t, "pk_test_legacy7890abcd", pubKey)
+
+	key, err = current.GetAPIKey(false)
+	require.NoError(t, err)
+	require.Equal(t, "sk_test_current890abcd", key)
+
+	pubKey, err = current.GetPublishableKey(false)
+	require.NoError(t, err)
+	require.Equal(t, "pk_test_current890abcd", pubKey)
+
+	// Reading the legacy fields must not leave aliases behind that would
+	// redirect writes to the canonical fields
+	viper.Set(legacy.GetConfigField(TestModeAPIKeyName), "sk_test_rotated890abcd")
+	require.Equal(t, "sk_test_legacy7890abcd", viper.GetString(legacy.GetConfigField("secret_key")))
+}
+
+func TestPrecedence(t *testing.T) {
+	defer WithTempConfig(t, `[tests]
+device_name = 'from-config'
+test_mode_api_key = 'sk_test_fromconfig0000'
+`)()
+
+	tests := []struct {
+		name           string
+		env            bool
+		profile        bool
+		wantKey        string
+		wantDeviceName string
+	}{
+		{"env over profile and config", true, true, "sk_test_fromenv0000000", "from-env"},
+		{"env over config", true, false, "sk_test_fromenv0000000", "from-env"},
+		{"profile over config", false, true, "sk_test_fromprofile000", "from-profile"},
+		{"config", false, false, "sk_test_fromconfig0000", "from-config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env {
+				t.Setenv("STRIPE_API_KEY", "sk_test_fromenv0000000")
+				t.Setenv("STRIPE_DEVICE_NAME", "from-env")
+			}
+
+			p := Profile{ProfileName: "tests"}
+			if tt.profile {
+				p.APIKey = "sk_test_fromprofile000"
+				p.DeviceName = "from-profile"
+			}
+
+			key, err := p.GetAPIKey(false)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantKey, key)
+
+			deviceName, err := p.GetDeviceName()
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDeviceName, deviceName)
+		})
+	}
+
+	// InitConfig defaults the device name, which must not hide the config
+	// file, while --device-name still takes precedence over it
+	defer func() { KeyRing = nil }()
+
+	for _, tt := range []struct {
+		name           string
+		flag           string
+		wantDeviceName string
+	}{
+		{"config through InitConfig", "", "from-config"},
+		{"flag through InitConfig", "from-flag", "from-flag"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{
+				Color:        "auto",
+				LogLevel:     "info",
+				Profile:      Profile{ProfileName: "tests", DeviceName: tt.flag},
+				ProfilesFile: viper.ConfigFileUsed(),
+			}
+			c.InitConfig()
+
+			key, err := c.Profile.GetAPIKey(false)
+			require.NoError(t, err)
+			require.Equal(t, "sk_test_fromconfig0000", key)
+
+			deviceName, err := c.Profile.GetDeviceName()
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDeviceName, deviceName)
+		})
+	}
+}
+
+func TestReconcileExpiry(t *testing.T) {
+	defer WithTempConfig(t, `[tests]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '2022-09-01'
+`)()
+
+	var changes []string
+	OnConfigChange = func(profile, field, action string) {
+		changes = append(changes, profile+"."+field+":"+action)
+	}
+	defer func() { OnConfigChange = nil }()
+
+	p := Profile{ProfileName: "tests"}
+	serverExpiry := time.Date(2022, 11, 30, 23, 0, 0, 0, time.FixedZone("PST", -8*60*60))
+
+	require.NoError(t, p.ReconcileExpiry(false, serverExpiry))
+
+	expiresAt, err := p.GetExpiresAt(false)
+	require.NoError(t, err)
+	require.True(t, time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC).Equal(expiresAt))
+
+	// The same date again doesn't rewrite the config
+	require.NoError(t, p.ReconcileExpiry(false, serverExpiry))
+	require.Equal(t, []string{"tests.test_mode_key_expires_at:write"}, changes)
+
+	require.NoError(t, p.ReconcileExpiry(true, serverExpiry))
+	expiresAt, err = p.GetExpiresAt(true)
+	require.NoError(t, err)
+	require.True(t, time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC).Equal(expiresAt))
+
+	require.Error(t, p.ReconcileExpiry(false, time.Time{}))
+}
+
+func TestWriteProfileSanitizesDeviceName(t *testing.T) {
+	defer WithTempConfig(t, "")()
+
+	p := Profile{ProfileName: "tests", DeviceName: "Zoë's MacBook Pro"}
+	require.NoError(t, p.writeProfile(viper.New()))
+	require.NoError(t, viper.ReadInConfig())
+	require.Equal(t, "Zoes-MacBook-Pro", viper.GetString("tests."+DeviceNameName))
+
+	p.DeviceName = "日本語"
+	require.Error(t, p.writeProfile(viper.New()))
+}
+
+func TestSwapActiveKey(t *testing.T) {
+	defer WithTempConfig(t, `[tests]
+test_mode_api_key = 'sk_test_old4567890abcd'
+test_mode_key_expires_at = '2022-09-01'
+`)()
+
+	p := Profile{ProfileName: "tests"}
+
+	revert, err := p.SwapActiveKey(false, "sk_test_new4567890abcd")
+	require.NoError(t, err)
+
+	key, err := p.GetAPIKey(false)
+	require.NoError(t, err)
+	require.Equal(t, "sk_test_new4567890abcd", key)
+	require.Equal(t, "sk_test_new4567890abcd", p.TestModeAPIKey)
+	require.Equal(t, getKeyExpiresAt(), viper.GetString("tests."+TestModeKeyExpiresAtName))
+
+	revert()
+
+	key, err = p.GetAPIKey(false)
+	require.NoError(t, err)
+	require.Equal(t, "sk_test_old4567890abcd", key)
+	require.Empty(t, p.TestModeAPIKey)
+	require.Equal(t, "2022-09-01", viper.GetString("tests."+TestModeKeyExpiresAtName))
+
+	// Invalid keys and keys for the other mode are refused
+	for _, newKey := range []string{"sk_short", "pk_test_new4567890abcd", "sk_live_new4567890abcd"} {
+		_, err = p.SwapActiveKey(false, newKey)
+		require.Error(t, err, newKey)
+	}
+
+	key, err = p.GetAPIKey(false)
+	require.NoError(t, err)
+	require.Equal(t, "sk_test_old4567890abcd", key)
+}
+
+func TestTouch(t *testing.T) {
+	contents := `# managed by hand
+color = 'auto'
+
+[tests] # the test profile
+device_name = 'st-testing'
+last_used_at = '2022-01-01T00:00:00Z'
+
+[other]
+device_name = 'st-other'
+`
+	defer WithTempConfig(t, contents)()
+
+	// Overrides must not end up in the file
+	viper.Set("color", "on")
+
+	p := Profile{ProfileName: "tests"}
+	require.NoError(t, p.Touch())
+
+	written, err := os.ReadFile(viper.ConfigFileUsed())
+	require.NoError(t, err)
+	require.Contains(t, string(written), "# managed by hand\ncolor = 'auto'\n")
+	require.NotContains(t, string(written), "2022-01-01")
+
+	require.NoError(t, viper.ReadInConfig())
+	lastUsedAt, err := p.GetLastUsedAt()
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), lastUsedAt, time.Minute)
+
+	// The field is added to profiles that don't have it yet
+	other := Profile{ProfileName: "other"}
+	require.NoError(t, other.Touch())
+	require.NoError(t, viper.ReadInConfig())
+	_, err = other.GetLastUsedAt()
+	require.NoError(t, err)
+	require.NotEmpty(t, viper.GetString("other."+LastUsedAtName))
+
+	// Profiles that aren't in the file are left alone
+	before, err := os.ReadFile(viper.ConfigFileUsed())
+	require.NoError(t, err)
+	require.NoError(t, (&Profile{ProfileName: "missing"}).Touch())
+	after, err := os.ReadFile(viper.ConfigFileUsed())
+	require.NoError(t, err)
+	require.Equal(t, string(before), string(after))
+}
+
+func TestTouchThenWriteConfigField(t *testing.T) {
+	defer WithTempConfig(t, "[tests]\nlast_used_at = '2022-01-01T00:00:00Z'\n")()
+
+	p := Profile{ProfileName: "tests"}
+	require.NoError(t, p.Touch())
+	require.NoError(t, p.WriteConfigField(DisplayNameName, "Tests"))
+
+	// The write must not put back the previous date
+	written, err := os.ReadFile(viper.ConfigFileUsed())
+	require.NoError(t, err)
+	require.NotContains(t, string(written), "2022-01-01")
+
+	lastUsedAt, err := p.GetLastUsedAt()
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), lastUsedAt, time.Minute)
+}
+
 func helperLoadBytes(t *testing.T, name string) []byte {
 	bytes, err := ioutil.ReadFile(name)
 	if err != nil {
@@ -0,0 +1,435 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/viper"
+
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+// LoadAllProfiles returns every profile found in the config file. Secret keys
+// are redacted; publishable keys and other fields are returned as is.
+//
+// Profiles that fail to load are still returned with the fields that could be
+// read, and the problems are aggregated in the returned error.
+func LoadAllProfiles() ([]*Profile, error) {
+	var problems []string
+	var profiles []*Profile
+
+	for _, name := range profileNames(viper.GetViper()) {
+		p := &Profile{
+			ProfileName:            name,
+			DeviceName:             viper.GetString(name + "." + DeviceNameName),
+			DisplayName:            viper.GetString(name + "." + DisplayNameName),
+			AccountID:              viper.GetString(name + "." + AccountIDName),
+			TestModePublishableKey: firstSet(name, testModePubKeyNames...),
+			LiveModePublishableKey: viper.GetString(name + "." + LiveModePubKeyName),
+			TerminalPOSDeviceID:    viper.GetString(name + ".terminal_pos_device_id"),
+		}
+
+		testKey := firstSet(name, testModeAPIKeyNames...)
+		if testKey != "" {
+			if err := validators.APIKey(testKey); err != nil {
+				problems = append(problems, fmt.Sprintf("%s.%s: %v", name, TestModeAPIKeyName, err))
+			} else {
+				p.TestModeAPIKey = RedactAPIKey(testKey)
+			}
+		}
+
+		liveKey := viper.GetString(name + "." + LiveModeAPIKeyName)
+		if liveKey != "" {
+			if err := validators.APIKey(liveKey); err != nil {
+				problems = append(problems, fmt.Sprintf("%s.%s: %v", name, LiveModeAPIKeyName, err))
+			} else {
+				p.LiveModeAPIKey = RedactAPIKey(liveKey)
+			}
+		}
+
+		profiles = append(profiles, p)
+	}
+
+	if len(problems) > 0 {
+		return profiles, fmt.Errorf("failed to load some profiles: %s", strings.Join(problems, "; "))
+	}
+
+	return profiles, nil
+}
+
+// FindDuplicateProfiles groups the names of profiles that appear to point to
+// the same account, because they share an account ID or the same test mode API
+// key. Profiles are grouped transitively, so a profile sharing its account ID
+// with one profile and its key with another joins both in a single group.
+// Only groups of two or more profiles are returned, with sorted names.
+func FindDuplicateProfiles() [][]string {
+	// parent links each profile to another one of its group, with the root of
+	// the group linking to itself
+	parent := make(map[string]string)
+
+	var find func(name string) string
+	find = func(name string) string {
+		if parent[name] != name {
+			parent[name] = find(parent[name])
+		}
+		return parent[name]
+	}
+
+	// owners maps each account ID and key fingerprint to the first profile
+	// found with it
+	owners := make(map[string]string)
+
+	for _, name := range profileNames(viper.GetViper()) {
+		var groupKeys []string
+
+		if accountID := viper.GetString(name + "." + AccountIDName); accountID != "" {
+			groupKeys = append(groupKeys, "account:"+accountID)
+		}
+		if key := firstSet(name, testModeAPIKeyNames...); key != "" {
+			groupKeys = append(groupKeys, "key:"+keyFingerprint(key))
+		}
+
+		if len(groupKeys) == 0 {
+			continue
+		}
+
+		parent[name] = name
+
+		for _, groupKey := range groupKeys {
+			if owner, ok := owners[groupKey]; ok {
+				parent[find(name)] = find(owner)
+			} else {
+				owners[groupKey] = name
+			}
+		}
+	}
+
+	groups := make(map[string][]string)
+	for name := range parent {
+		root := find(name)
+		groups[root] = append(groups[root], name)
+	}
+
+	var duplicates [][]string
+	for _, names := range groups {
+		if len(names) > 1 {
+			sort.Strings(names)
+			duplicates = append(duplicates, names)
+		}
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i][0] < duplicates[j][0]
+	})
+
+	return duplicates
+}
+
+// ListProfilesByGroup returns the sorted names of the profiles in each group.
+// Profiles that don't belong to a group are listed under the empty string.
+func ListProfilesByGroup() map[string][]string {
+	groups := make(map[string][]string)
+
+	for _, name := range profileNames(viper.GetViper()) {
+		group := viper.GetString(name + "." + GroupName)
+		groups[group] = append(groups[group], name)
+	}
+
+	return groups
+}
+
+// legacyFields lists the fields that used to be stored under other names,
+// along with these legacy names.
+var legacyFields = []struct {
+	canonical string
+	legacy    []string
+}{
+	{TestModeAPIKeyName, testModeAPIKeyNames[1:]},
+	{TestModePubKeyName, testModePubKeyNames[1:]},
+}
+
+// MigrateAllProfiles rewrites every profile in the config file to only use
+// canonical field names: legacy key fields are moved to their canonical name,
+// or removed if the canonical field is already set, and missing or unreadable
+// key expiration dates are set. The config file is only written if something
+// changed. The returned report lists each change, prefixed by the profile
+// name.
+func MigrateAllProfiles() ([]string, error) {
+	type change struct {
+		profile, field, action string
+	}
+
+	var report []string
+	var changes []change
+
+	settings, err := readSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	record := func(name, field, action, msg string) {
+		report = append(report, fmt.Sprintf("[%s] %s", name, msg))
+		changes = append(changes, change{name, field, action})
+	}
+
+	for _, name := range settingsProfileNames(settings) {
+		table := settings[name].(map[string]interface{})
+
+		for _, fields := range legacyFields {
+			for _, legacy := range fields.legacy {
+				value, ok := table[legacy]
+				if !ok {
+					continue
+				}
+
+				delete(table, legacy)
+
+				if current, _ := table[fields.canonical].(string); current == "" && fmt.Sprint(value) != "" {
+					table[fields.canonical] = fmt.Sprint(value)
+					record(name, fields.canonical, ConfigChangeWrite, fmt.Sprintf("moved %s to %s", legacy, fields.canonical))
+				} else {
+					record(name, legacy, ConfigChangeDelete, fmt.Sprintf("removed stale %s", legacy))
+				}
+			}
+		}
+
+		for _, fields := range [][2]string{
+			{TestModeAPIKeyName, TestModeKeyExpiresAtName},
+			{LiveModeAPIKeyName, LiveModeKeyExpiresAtName},
+		} {
+			if key, _ := table[fields[0]].(string); key == "" {
+				continue
+			}
+
+			value, _ := table[fields[1]].(string)
+			if _, ok := parseExpiresAt(value); ok {
+				continue
+			}
+
+			table[fields[1]] = getKeyExpiresAt()
+			record(name, fields[1], ConfigChangeWrite, fmt.Sprintf("set %s", fields[1]))
+		}
+	}
+
+	if len(changes) == 0 {
+		return report, nil
+	}
+
+	err = writeSettings(settings)
+	if err != nil {
+		return report, err
+	}
+
+	for _, c := range changes {
+		notifyConfigChange(c.profile, c.field, c.action)
+	}
+
+	return report, nil
+}
+
+// RefreshAllExpiries sets the expiration date of every configured key, in
+// every profile, to KeyValidInDays from now. The returned list has the names
+// of the profiles whose dates changed.
+func RefreshAllExpiries() ([]string, error) {
+	var updated []string
+	var changes [][2]string
+
+	settings, err := readSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := getKeyExpiresAt()
+
+	for _, name := range settingsProfileNames(settings) {
+		table := settings[name].(map[string]interface{})
+		changed := false
+
+		for _, fields := range []struct {
+			keys      []string
+			expiresAt string
+		}{
+			{testModeAPIKeyNames, TestModeKeyExpiresAtName},
+			{[]string{LiveModeAPIKeyName}, LiveModeKeyExpiresAtName},
+		} {
+			configured := false
+			for _, field := range fields.keys {
+				if key, _ := table[field].(string); key != "" {
+					configured = true
+				}
+			}
+
+			if !configured || table[fields.expiresAt] == expiresAt {
+				continue
+			}
+
+			table[fields.expiresAt] = expiresAt
+			changes = append(changes, [2]string{name, fields.expiresAt})
+			changed = true
+		}
+
+		if changed {
+			updated = append(updated, name)
+		}
+	}
+
+	if len(changes) == 0 {
+		return updated, nil
+	}
+
+	err = writeSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range changes {
+		notifyConfigChange(c[0], c[1], ConfigChangeWrite)
+	}
+
+	return updated, nil
+}
+
+// KeyExpiry is the expiration date of the key of a profile for a mode
+type KeyExpiry struct {
+	Profile   string
+	Mode      string
+	ExpiresAt time.Time
+}
+
+// ExpiringKeys returns the keys of every profile that expire within the given
+// duration from now, including the keys that already expired, sorted by
+// expiration date. Keys without an expiration date are skipped; unreadable
+// dates don't prevent the other keys from being returned and are aggregated
+// in the returned error.
+func ExpiringKeys(within time.Duration) ([]KeyExpiry, error) {
+	var problems []string
+	var expiring []KeyExpiry
+
+	deadline := time.Now().Add(within)
+
+	for _, name := range profileNames(viper.GetViper()) {
+		for _, mode := range []struct {
+			name      string
+			keys      []string
+			expiresAt string
+		}{
+			{TestMode, testModeAPIKeyNames, TestModeKeyExpiresAtName},
+			{LiveMode, []string{LiveModeAPIKeyName}, LiveModeKeyExpiresAtName},
+		} {
+			value := viper.GetString(name + "." + mode.expiresAt)
+			if value == "" || firstSet(name, mode.keys...) == "" {
+				continue
+			}
+
+			expiresAt, ok := parseExpiresAt(value)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: unable to parse date %q", name, mode.expiresAt, value))
+				continue
+			}
+
+			if !expiresAt.After(deadline) {
+				expiring = append(expiring, KeyExpiry{Profile: name, Mode: mode.name, ExpiresAt: expiresAt})
+			}
+		}
+	}
+
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].ExpiresAt.Before(expiring[j].ExpiresAt)
+	})
+
+	if len(problems) > 0 {
+		return expiring, fmt.Errorf("failed to read some expiration dates: %s", strings.Join(problems, "; "))
+	}
+
+	return expiring, nil
+}
+
+// readSettings decodes the config file as is, without the flags, overrides and
+// defaults that viper layers on top of it, so that the settings can be
+// rewritten by writeSettings.
+func readSettings() (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+
+	_, err := toml.DecodeFile(viper.ConfigFileUsed(), &settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// settingsProfileNames returns the sorted names of all the profiles in
+// settings, as returned by readSettings.
+func settingsProfileNames(settings map[string]interface{}) []string {
+	var names []string
+
+	for field, value := range settings {
+		if isProfile(value) {
+			names = append(names, field)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// writeSettings replaces the contents of the config file with settings, as
+// returned by readSettings, and reloads it so that later viper writes don't
+// put back the previous settings.
+func writeSettings(settings map[string]interface{}) error {
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(settings)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(viper.ConfigFileUsed(), buf.Bytes(), ConfigFilePermissions)
+	if err != nil {
+		return err
+	}
+
+	return viper.ReadInConfig()
+}
+
+// keyFingerprint returns a digest identifying a key without revealing it.
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// profileNames returns the sorted names of all the profiles in the config
+// loaded by v.
+func profileNames(v *viper.Viper) []string {
+	var names []string
+
+	for field, value := range v.AllSettings() {
+		if isProfile(value) {
+			names = append(names, field)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// firstSet returns the value of the first of the given fields that is set on
+// the named profile. It's used to read fields that may be stored under a
+// legacy name.
+func firstSet(profileName string, fields ...string) string {
+	for _, field := range fields {
+		if value := viper.GetString(profileName + "." + field); value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadAllProfiles(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	viper.Set("color", "auto")
+	viper.Set("default.device_name", "st-testing")
+	viper.Set("default.test_mode_api_key", "sk_test_1234567890abcd")
+	viper.Set("default.test_mode_pub_key", "pk_test_1234567890abcd")
+	viper.Set("legacy.secret_key", "sk_test_legacy7890abcd")
+	viper.Set("broken.test_mode_api_key", "sk_short")
+
+	profiles, err := LoadAllProfiles()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "broken.test_mode_api_key")
+	require.Len(t, profiles, 3)
+
+	require.Equal(t, "broken", profiles[0].ProfileName)
+	require.Empty(t, profiles[0].TestModeAPIKey)
+
+	require.Equal(t, "default", profiles[1].ProfileName)
+	require.Equal(t, "st-testing", profiles[1].DeviceName)
+	require.Equal(t, "sk_test_**********abcd", profiles[1].TestModeAPIKey)
+	require.Equal(t, "pk_test_1234567890abcd", profiles[1].TestModePublishableKey)
+
+	require.Equal(t, "legacy", profiles[2].ProfileName)
+	require.Equal(t, "sk_test_**********abcd", profiles[2].TestModeAPIKey)
+}
+
+func TestFindDuplicateProfiles(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	viper.Set("a.account_id", "acct_123")
+	viper.Set("b.account_id", "acct_123")
+	viper.Set("c.account_id", "acct_456")
+	viper.Set("d.test_mode_api_key", "sk_test_1234567890abcd")
+	viper.Set("e.secret_key", "sk_test_1234567890abcd")
+	viper.Set("f.device_name", "st-testing")
+
+	require.Equal(t, [][]string{{"a", "b"}, {"d", "e"}}, FindDuplicateProfiles())
+}
+
+func TestFindDuplicateProfilesMixed(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	// a and b share an account ID, b and c a key, so they're all duplicates
+	viper.Set("a.account_id", "acct_123")
+	viper.Set("b.account_id", "acct_123")
+	viper.Set("b.test_mode_api_key", "sk_test_1234567890abcd")
+	viper.Set("c.test_mode_api_key", "sk_test_1234567890abcd")
+	viper.Set("d.account_id", "acct_456")
+	viper.Set("d.test_mode_api_key", "sk_test_0987654321abcd")
+
+	require.Equal(t, [][]string{{"a", "b", "c"}}, FindDuplicateProfiles())
+}
+
+func TestMigrateAllProfiles(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	profilesFile := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(profilesFile, []byte(`color = 'auto'
+
+[legacy]
+secret_key = 'sk_test_legacy7890abcd'
+publishable_key = 'pk_test_legacy7890abcd'
+
+[mixed]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '2030-01-01'
+api_key = 'sk_test_stale67890abcd'
+
+[clean]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '2030-01-01'
+`), 0600)
+	require.NoError(t, err)
+
+	viper.SetConfigFile(profilesFile)
+	require.NoError(t, viper.ReadInConfig())
+
+	// Flags, overrides and defaults must not be persisted
+	viper.Set("color", "on")
+	viper.SetDefault("legacy.device_name", "st-testing")
+
+	report, err := MigrateAllProfiles()
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"[legacy] moved secret_key to test_mode_api_key",
+		"[legacy] moved publishable_key to test_mode_pub_key",
+		"[legacy] set test_mode_key_expires_at",
+		"[mixed] removed stale api_key",
+	}, report)
+
+	var written map[string]interface{}
+	_, err = toml.DecodeFile(profilesFile, &written)
+	require.NoError(t, err)
+	require.Equal(t, "auto", written["color"])
+	require.NotContains(t, written["legacy"], "device_name")
+
+	require.Equal(t, "sk_test_legacy7890abcd", viper.GetString("legacy.test_mode_api_key"))
+	require.Equal(t, "pk_test_legacy7890abcd", viper.GetString("legacy.test_mode_pub_key"))
+	require.NotEmpty(t, viper.GetString("legacy.test_mode_key_expires_at"))
+	require.False(t, viper.IsSet("legacy.secret_key"))
+	require.False(t, viper.IsSet("mixed.api_key"))
+	require.Equal(t, "sk_test_1234567890abcd", viper.GetString("mixed.test_mode_api_key"))
+
+	// Running it again is a no-op
+	report, err = MigrateAllProfiles()
+	require.NoError(t, err)
+	require.Empty(t, report)
+}
+
+func TestMigrateAllProfilesThenWrite(t *testing.T) {
+	defer WithTempConfig(t, `[legacy]
+secret_key = 'sk_test_legacy7890abcd'
+test_mode_key_expires_at = '2030-01-01'
+`)()
+
+	_, err := MigrateAllProfiles()
+	require.NoError(t, err)
+
+	// Later writes in the same run must not bring back the legacy field
+	p := Profile{ProfileName: "legacy"}
+	require.NoError(t, p.WriteConfigField(DisplayNameName, "Legacy"))
+
+	var written map[string]interface{}
+	_, err = toml.DecodeFile(viper.ConfigFileUsed(), &written)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		TestModeAPIKeyName:       "sk_test_legacy7890abcd",
+		TestModeKeyExpiresAtName: "2030-01-01",
+		DisplayNameName:          "Legacy",
+	}, written["legacy"])
+}
+
+func TestListProfilesByGroup(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	profilesFile := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(profilesFile, []byte(`[a]
+device_name = 'a'
+
+[b]
+device_name = 'b'
+group = 'prod'
+`), 0600))
+
+	viper.SetConfigFile(profilesFile)
+	require.NoError(t, viper.ReadInConfig())
+
+	c := Profile{ProfileName: "c"}
+	require.NoError(t, c.SetGroup("prod"))
+
+	a := Profile{ProfileName: "a"}
+	require.NoError(t, a.SetGroup("staging"))
+
+	require.Equal(t, map[string][]string{
+		"prod":    {"b", "c"},
+		"staging": {"a"},
+	}, ListProfilesByGroup())
+
+	require.NoError(t, a.SetGroup(""))
+	require.Equal(t, "", a.GetGroup())
+	require.Equal(t, []string{"a"}, ListProfilesByGroup()[""])
+}
+
+func TestRefreshAllExpiries(t *testing.T) {
+	defer WithTempConfig(t, `color = 'auto'
+
+[both]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '2022-01-01'
+live_mode_api_key = 'sk_live_1234567890abcd'
+
+[legacy]
+secret_key = 'sk_test_legacy7890abcd'
+
+[nokeys]
+device_name = 'st-testing'
+`)()
+
+	// Flags and overrides must not be persisted
+	viper.Set("color", "on")
+
+	updated, err := RefreshAllExpiries()
+	require.NoError(t, err)
+	require.Equal(t, []string{"both", "legacy"}, updated)
+
+	var written map[string]interface{}
+	_, err = toml.DecodeFile(viper.ConfigFileUsed(), &written)
+	require.NoError(t, err)
+	require.Equal(t, "auto", written["color"])
+
+	expiresAt := getKeyExpiresAt()
+	require.Equal(t, expiresAt, viper.GetString("both."+TestModeKeyExpiresAtName))
+	require.Equal(t, expiresAt, viper.GetString("both."+LiveModeKeyExpiresAtName))
+	require.Equal(t, expiresAt, viper.GetString("legacy."+TestModeKeyExpiresAtName))
+	require.False(t, viper.IsSet("nokeys."+TestModeKeyExpiresAtName))
+
+	// Nothing left to refresh
+	updated, err = RefreshAllExpiries()
+	require.NoError(t, err)
+	require.Empty(t, updated)
+}
+
+func TestRefreshAllExpiriesThenWrite(t *testing.T) {
+	defer WithTempConfig(t, `[tests]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '2022-01-01'
+`)()
+
+	_, err := RefreshAllExpiries()
+	require.NoError(t, err)
+
+	// Later writes in the same run must not bring back the old date
+	p := Profile{ProfileName: "tests"}
+	require.NoError(t, p.WriteConfigField(DisplayNameName, "Tests"))
+
+	var written map[string]map[string]interface{}
+	_, err = toml.DecodeFile(viper.ConfigFileUsed(), &written)
+	require.NoError(t, err)
+	require.Equal(t, getKeyExpiresAt(), written["tests"][TestModeKeyExpiresAtName])
+	require.Equal(t, "Tests", written["tests"][DisplayNameName])
+}
+
+func TestExpiringKeys(t *testing.T) {
+	soon := time.Now().Add(48 * time.Hour).Format(DateStringFormat)
+	later := time.Now().Add(60 * 24 * time.Hour).Format(DateStringFormat)
+
+	defer WithTempConfig(t, `[both]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '`+later+`'
+live_mode_api_key = 'sk_live_1234567890abcd'
+live_mode_key_expires_at = '`+soon+`'
+
+[expired]
+secret_key = 'sk_test_legacy7890abcd'
+test_mode_key_expires_at = '2022-01-01'
+
+[broken]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = 'soon'
+
+[nokeys]
+test_mode_key_expires_at = '2022-01-01'
+`)()
+
+	expiring, err := ExpiringKeys(7 * 24 * time.Hour)
+	require.EqualError(t, err, `failed to read some expiration dates: broken.test_mode_key_expires_at: unable to parse date "soon"`)
+
+	expiredAt, _ := parseExpiresAt("2022-01-01")
+	soonAt, _ := parseExpiresAt(soon)
+	require.Equal(t, []KeyExpiry{
+		{Profile: "expired", Mode: TestMode, ExpiresAt: expiredAt},
+		{Profile: "both", Mode: LiveMode, ExpiresAt: soonAt},
+	}, expiring)
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+// ErrLiveModeLocked is returned when a live mode key is requested for a
+// profile with live_mode_locked set
+var ErrLiveModeLocked = errors.New("live mode is locked for this profile, unset live_mode_locked to use it")
+
+// GetDefaultMode returns the mode used by commands that don't explicitly ask
+// for live mode, as set by the default_mode field. It defaults to test mode.
+func (p *Profile) GetDefaultMode() string {
+	if strings.EqualFold(viper.GetString(p.GetConfigField(DefaultModeName)), LiveMode) {
+		return LiveMode
+	}
+
+	return TestMode
+}
+
+// LiveModeLocked returns whether live mode keys are prevented from being used
+// with the profile, as set by the live_mode_locked field.
+func (p *Profile) LiveModeLocked() bool {
+	return viper.GetBool(p.GetConfigField(LiveModeLockedName))
+}
+
+// ResolveKey returns the API key to use for a command, along with its mode.
+// Live mode is used when requestLive is set, e.g. by a --live flag, or when
+// the profile's default_mode is live.
+//
+// Keys provided through STRIPE_API_KEY or --api-key must be for the resolved
+// mode, or validators.ErrAPIKeyModeMismatch is returned. Stored keys must not
+// have expired. Live mode keys are refused with ErrLiveModeLocked when the
+// profile has live_mode_locked set.
+func (p *Profile) ResolveKey(requestLive bool) (string, string, error) {
+	mode := p.GetDefaultMode()
+	if requestLive {
+		mode = LiveMode
+	}
+
+	explicitKey := os.Getenv("STRIPE_API_KEY")
+	if explicitKey == "" {
+		explicitKey = p.APIKey
+	}
+
+	if explicitKey != "" {
+		if err := validators.APIKey(explicitKey); err != nil {
+			return "", "", err
+		}
+
+		explicitMode, err := keyMode(explicitKey)
+		if err != nil {
+			return "", "", err
+		}
+
+		if explicitMode != mode {
+			return "", "", validators.ErrAPIKeyModeMismatch
+		}
+
+		if mode == LiveMode && p.LiveModeLocked() {
+			return "", "", ErrLiveModeLocked
+		}
+
+		return explicitKey, mode, nil
+	}
+
+	livemode := mode == LiveMode
+
+	if livemode && p.LiveModeLocked() {
+		return "", "", ErrLiveModeLocked
+	}
+
+	key, err := p.GetAPIKey(livemode)
+	if err != nil {
+		return "", "", err
+	}
+
+	if expiresAt, err := p.GetExpiresAt(livemode); err == nil && !time.Now().Before(expiresAt) {
+		return "", "", validators.ErrAPIKeyExpired
+	}
+
+	return key, mode, nil
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/99designs/keyring"
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+func TestResolveKey(t *testing.T) {
+	defer func() { KeyRing = nil }()
+	KeyRing = keyring.NewArrayKeyring(nil)
+
+	future := time.Now().Add(24 * time.Hour).Format(DateStringFormat)
+
+	defer WithTempConfig(t, `[default]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '`+future+`'
+live_mode_api_key = 'sk_live_1234567890abcd'
+live_mode_key_expires_at = '`+future+`'
+use_keyring = false
+
+[livefirst]
+default_mode = 'live'
+test_mode_api_key = 'sk_test_1234567890abcd'
+live_mode_api_key = 'sk_live_1234567890abcd'
+use_keyring = false
+
+[locked]
+default_mode = 'live'
+live_mode_locked = true
+test_mode_api_key = 'sk_test_1234567890abcd'
+live_mode_api_key = 'sk_live_1234567890abcd'
+use_keyring = false
+
+[expired]
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '2022-01-01'
+`)()
+
+	p := &Profile{ProfileName: "default"}
+
+	key, mode, err := p.ResolveKey(false)
+	require.NoError(t, err)
+	require.Equal(t, "sk_test_1234567890abcd", key)
+	require.Equal(t, TestMode, mode)
+
+	key, mode, err = p.ResolveKey(true)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_1234567890abcd", key)
+	require.Equal(t, LiveMode, mode)
+
+	_, mode, err = (&Profile{ProfileName: "livefirst"}).ResolveKey(false)
+	require.NoError(t, err)
+	require.Equal(t, LiveMode, mode)
+
+	_, _, err = (&Profile{ProfileName: "locked"}).ResolveKey(false)
+	require.ErrorIs(t, err, ErrLiveModeLocked)
+
+	_, _, err = (&Profile{ProfileName: "expired"}).ResolveKey(false)
+	require.ErrorIs(t, err, validators.ErrAPIKeyExpired)
+
+	_, _, err = (&Profile{ProfileName: "missing"}).ResolveKey(false)
+	require.ErrorIs(t, err, validators.ErrAPIKeyNotConfigured)
+}
+
+func TestResolveKeyExplicit(t *testing.T) {
+	defer WithTempConfig(t, `[locked]
+live_mode_locked = true
+`)()
+
+	p := &Profile{ProfileName: "default", APIKey: "sk_live_1234567890abcd"}
+
+	key, mode, err := p.ResolveKey(true)
+	require.NoError(t, err)
+	require.Equal(t, "sk_live_1234567890abcd", key)
+	require.Equal(t, LiveMode, mode)
+
+	// A live key must not be used when test mode is expected
+	_, _, err = p.ResolveKey(false)
+	require.ErrorIs(t, err, validators.ErrAPIKeyModeMismatch)
+
+	_, _, err = (&Profile{ProfileName: "locked", APIKey: "sk_live_1234567890abcd"}).ResolveKey(true)
+	require.ErrorIs(t, err, ErrLiveModeLocked)
+
+	t.Setenv("STRIPE_API_KEY", "sk_test_1234567890abcd")
+
+	_, _, err = p.ResolveKey(true)
+	require.ErrorIs(t, err, validators.ErrAPIKeyModeMismatch)
+
+	key, mode, err = p.ResolveKey(false)
+	require.NoError(t, err)
+	require.Equal(t, "sk_test_1234567890abcd", key)
+	require.Equal(t, TestMode, mode)
+}
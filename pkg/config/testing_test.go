@@ -0,0 +1,27 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTempConfig(t *testing.T) {
+	cleanup := WithTempConfig(t, "[tests]\ntest_mode_api_key = 'sk_test_1234567890abcd'\n")
+
+	p := Profile{ProfileName: "tests"}
+	key, err := p.GetAPIKey(false)
+	require.NoError(t, err)
+	require.Equal(t, "sk_test_1234567890abcd", key)
+
+	// Writes go to the temporary file too
+	require.NoError(t, p.WriteConfigField(DisplayNameName, "Round trip"))
+	contents, err := os.ReadFile(viper.ConfigFileUsed())
+	require.NoError(t, err)
+	require.Contains(t, string(contents), "display_name = 'Round trip'")
+
+	cleanup()
+	require.Empty(t, viper.ConfigFileUsed())
+}
@@ -0,0 +1,256 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	exec "golang.org/x/sys/execabs"
+
+	"github.com/spf13/viper"
+
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+// ValidateConfigFile reads the config file at path and checks every profile
+// it contains. The returned error is only set when the file can't be read or
+// parsed at all; problems found in a readable file are reported in problems,
+// each prefixed with the profile and field it pertains to.
+func ValidateConfigFile(path string) ([]string, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("toml")
+
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+
+	if color := v.GetString("color"); color != "" {
+		if problem := validateColor(color); problem != "" {
+			problems = append(problems, "color: "+problem)
+		}
+	}
+
+	if value := v.GetString(RedactionVisibleCharsName); value != "" {
+		if _, err := strconv.Atoi(value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s is not a number of characters", RedactionVisibleCharsName, value))
+		}
+	}
+
+	for _, name := range profileNames(v) {
+		problems = append(problems, validateProfile(v, name)...)
+	}
+
+	return problems, nil
+}
+
+// Validate checks the fields of the profile stored in the config file and
+// returns a description of each problem found.
+func (p *Profile) Validate() []string {
+	return validateProfile(viper.GetViper(), p.ProfileName)
+}
+
+// validateProfile checks the fields of the named profile in the config
+// loaded by v.
+func validateProfile(v *viper.Viper, name string) []string {
+	var problems []string
+
+	check := func(field, problem string) {
+		if problem != "" {
+			problems = append(problems, fmt.Sprintf("[%s] %s: %s", name, field, problem))
+		}
+	}
+
+	get := func(field string) string {
+		return v.GetString(name + "." + field)
+	}
+
+	for _, field := range testModeAPIKeyNames {
+		if key := get(field); key != "" {
+			check(field, validateKey(key, TestMode))
+		}
+	}
+
+	if key := get(LiveModeAPIKeyName); key != "" {
+		check(LiveModeAPIKeyName, validateKey(key, LiveMode))
+	}
+
+	for _, pubKey := range []struct{ field, prefix string }{
+		{TestModePubKeyName, "pk_test_"},
+		{"publishable_key", "pk_test_"},
+		{"test_mode_publishable_key", "pk_test_"},
+		{LiveModePubKeyName, "pk_live_"},
+	} {
+		if key := get(pubKey.field); key != "" && !strings.HasPrefix(key, pubKey.prefix) {
+			check(pubKey.field, fmt.Sprintf("publishable key should start with %s", pubKey.prefix))
+		}
+	}
+
+	for _, field := range []string{TestModeKeyExpiresAtName, LiveModeKeyExpiresAtName} {
+		if value := get(field); value != "" {
+			if _, ok := parseExpiresAt(value); !ok {
+				check(field, fmt.Sprintf("unable to parse date %q", value))
+			}
+		}
+	}
+
+	for _, field := range []string{AccountIDName, DefaultConnectAccountName} {
+		if accountID := get(field); accountID != "" && !strings.HasPrefix(accountID, "acct_") {
+			check(field, "account ID should start with acct_")
+		}
+	}
+
+	if color := get("color"); color != "" {
+		check("color", validateColor(color))
+	}
+
+	for _, caCerts := range []struct{ field, file, dir string }{
+		{CACertFileName, get(CACertFileName), ""},
+		{CACertDirName, "", get(CACertDirName)},
+	} {
+		paths, err := caCertPaths(caCerts.file, caCerts.dir)
+		if err != nil {
+			check(caCerts.field, err.Error())
+			continue
+		}
+
+		for _, path := range paths {
+			if _, err := readCACerts(path); err != nil {
+				check(caCerts.field, err.Error())
+			}
+		}
+	}
+
+	if format := get(OutputFormatName); format != "" {
+		check(OutputFormatName, validateOutputFormat(format))
+	}
+
+	if mode := get(DefaultModeName); mode != "" {
+		check(DefaultModeName, validateMode(mode))
+	}
+
+	if version := get(APIVersionName); version != "" {
+		check(APIVersionName, validateAPIVersion(version))
+	}
+
+	return problems
+}
+
+// validateField checks a value about to be written to a profile field. Only
+// known fields are checked, others are always valid.
+func validateField(field, value string) error {
+	var problem string
+
+	switch field {
+	case "color":
+		problem = validateColor(value)
+	case OutputFormatName:
+		problem = validateOutputFormat(value)
+	case APITimeoutName:
+		if _, err := parseAPITimeout(value); err != nil {
+			problem = err.Error()
+		}
+	case APIVersionName:
+		problem = validateAPIVersion(value)
+	case AccountIDName:
+		if !strings.HasPrefix(value, "acct_") {
+			problem = "account ID should start with acct_"
+		}
+	case ConfirmLiveWritesName:
+		if _, err := strconv.ParseBool(value); err != nil {
+			problem = fmt.Sprintf("%s is not true or false", value)
+		}
+	case EditorName:
+		if fields := strings.Fields(value); len(fields) == 0 {
+			problem = "the editor can't be empty"
+		} else if _, err := exec.LookPath(fields[0]); err != nil {
+			problem = fmt.Sprintf("%s can't be found in your PATH", fields[0])
+		}
+	case DefaultModeName:
+		problem = validateMode(value)
+	case LiveModeLockedName:
+		if _, err := strconv.ParseBool(value); err != nil {
+			problem = fmt.Sprintf("%s is not true or false", value)
+		}
+	case DefaultConnectAccountName:
+		if value != "" && !strings.HasPrefix(value, "acct_") {
+			problem = "account ID should start with acct_"
+		}
+	case TestModeAPIKeyName, LiveModeAPIKeyName:
+		if err := validators.APIKey(value); err != nil {
+			problem = err.Error()
+		}
+	}
+
+	if problem != "" {
+		return fmt.Errorf("invalid value for %s: %s", field, problem)
+	}
+
+	return nil
+}
+
+// validateKey returns a description of the problem with a secret key stored for
+// the given mode, or an empty string if the key is valid.
+func validateKey(key, expectedMode string) string {
+	mode, err := keyMode(key)
+	if err != nil {
+		return err.Error()
+	}
+
+	if mode != expectedMode {
+		return fmt.Sprintf("expected a %s mode key but found a %s mode key", expectedMode, mode)
+	}
+
+	return ""
+}
+
+// validateColor returns a description of the problem with a color setting, or
+// an empty string if it is valid.
+func validateColor(color string) string {
+	switch color {
+	case ColorOn, ColorOff, ColorAuto:
+		return ""
+	default:
+		return fmt.Sprintf("%s is not one of %s, %s, %s", color, ColorOn, ColorOff, ColorAuto)
+	}
+}
+
+// validateMode returns a description of the problem with a default mode
+// setting, or an empty string if it is valid.
+func validateMode(mode string) string {
+	switch strings.ToLower(mode) {
+	case TestMode, LiveMode:
+		return ""
+	default:
+		return fmt.Sprintf("%s is not one of %s, %s", mode, TestMode, LiveMode)
+	}
+}
+
+// validateAPIVersion checks that version is a YYYY-MM-DD API version. An empty
+// version unpins it and is valid.
+func validateAPIVersion(version string) string {
+	if version == "" {
+		return ""
+	}
+
+	if _, err := time.Parse("2006-01-02", version); err != nil {
+		return fmt.Sprintf("%s is not an API version such as 2022-08-01", version)
+	}
+
+	return ""
+}
+
+// validateOutputFormat returns a description of the problem with an output
+// format setting, or an empty string if it is valid.
+func validateOutputFormat(format string) string {
+	switch strings.ToLower(format) {
+	case OutputFormatTable, OutputFormatJSON:
+		return ""
+	default:
+		return fmt.Sprintf("%s is not one of %s, %s", format, OutputFormatTable, OutputFormatJSON)
+	}
+}
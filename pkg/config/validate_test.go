@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(path, []byte(`color = 'sometimes'
+redaction_visible_chars = 'lots'
+
+[default]
+account_id = 'acct_123'
+test_mode_api_key = 'sk_test_1234567890abcd'
+test_mode_key_expires_at = '2022-09-01'
+test_mode_pub_key = 'pk_test_1234567890abcd'
+
+[broken]
+account_id = '123'
+test_mode_api_key = 'sk_live_1234567890abcd'
+live_mode_key_expires_at = 'soon'
+live_mode_pub_key = 'pk_test_1234567890abcd'
+`), 0600)
+	require.NoError(t, err)
+
+	problems, err := ValidateConfigFile(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"color: sometimes is not one of on, off, auto",
+		"redaction_visible_chars: lots is not a number of characters",
+		"[broken] test_mode_api_key: expected a test mode key but found a live mode key",
+		"[broken] live_mode_pub_key: publishable key should start with pk_live_",
+		"[broken] live_mode_key_expires_at: unable to parse date \"soon\"",
+		"[broken] account_id: account ID should start with acct_",
+	}, problems)
+}
+
+func TestValidateConfigFileUnparseable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	err := os.WriteFile(path, []byte("[default\n"), 0600)
+	require.NoError(t, err)
+
+	_, err = ValidateConfigFile(path)
+	require.Error(t, err)
+}
+
+func TestWriteConfigFieldValidation(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	viper.SetConfigFile(filepath.Join(t.TempDir(), "config.toml"))
+	viper.SetConfigType("toml")
+
+	p := Profile{ProfileName: "default"}
+
+	for field, value := range map[string]string{
+		"color":                   "sometimes",
+		AccountIDName:             "123",
+		TestModeAPIKeyName:        "sk_short",
+		OutputFormatName:          "yaml",
+		APITimeoutName:            "forever",
+		APIVersionName:            "2020-08",
+		DefaultConnectAccountName: "123",
+		ConfirmLiveWritesName:     "sometimes",
+	} {
+		err := p.WriteConfigField(field, value)
+		require.Error(t, err, field)
+		require.Contains(t, err.Error(), "invalid value for "+field)
+		require.False(t, viper.IsSet(p.GetConfigField(field)), field)
+	}
+
+	require.NoError(t, p.WriteConfigField("color", ColorOff))
+	require.NoError(t, p.WriteConfigField(AccountIDName, "acct_123"))
+	require.NoError(t, p.WriteConfigField(TestModeAPIKeyName, "sk_test_1234567890abcd"))
+	require.NoError(t, p.WriteConfigField("anything", "goes"))
+}
+
+func TestSetDefaultConnectAccount(t *testing.T) {
+	defer WithTempConfig(t, "")()
+
+	p := Profile{ProfileName: "default"}
+	require.Empty(t, p.GetDefaultConnectAccount())
+
+	require.NoError(t, p.SetDefaultConnectAccount(" acct_123 "))
+	require.Equal(t, "acct_123", p.GetDefaultConnectAccount())
+
+	require.Error(t, p.SetDefaultConnectAccount("123"))
+	require.Equal(t, "acct_123", p.GetDefaultConnectAccount())
+
+	require.NoError(t, p.SetDefaultConnectAccount(""))
+	require.Empty(t, p.GetDefaultConnectAccount())
+}
+
+func TestSetAPIVersion(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	viper.SetConfigFile(filepath.Join(t.TempDir(), "config.toml"))
+	viper.SetConfigType("toml")
+
+	p := Profile{ProfileName: "default"}
+	require.Empty(t, p.GetAPIVersion())
+
+	require.NoError(t, p.SetAPIVersion(" 2020-08-27 "))
+	require.Equal(t, "2020-08-27", p.GetAPIVersion())
+
+	require.Error(t, p.SetAPIVersion("2020-13-01"))
+	require.Equal(t, "2020-08-27", p.GetAPIVersion())
+
+	require.NoError(t, p.SetAPIVersion(""))
+	require.Empty(t, p.GetAPIVersion())
+}
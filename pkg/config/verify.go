@@ -0,0 +1,59 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+// verifyAPIBaseURL is the base URL of the API used by VerifyAPIKeyOnline
+var verifyAPIBaseURL = "https://api.stripe.com"
+
+// VerifyAPIKeyOnline checks that Stripe accepts the API key of the given mode
+// by retrieving the account it belongs to. Unlike the format checks done when
+// reading the key, it returns validators.ErrAPIKeyRejected for keys that are
+// well formed but revoked or rolled. Other errors mean that the key couldn't
+// be checked, e.g. because the network is unavailable or ctx was canceled.
+// The client of the profile returned by HTTPClient is used when client is nil.
+func (p *Profile) VerifyAPIKeyOnline(ctx context.Context, client *http.Client, livemode bool) error {
+	key, err := p.GetAPIKey(livemode)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, verifyAPIBaseURL+"/v1/account", nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(key, "")
+
+	if client == nil {
+		client, err = p.HTTPClient()
+		if err != nil {
+			return err
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to verify the API key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return validators.ErrAPIKeyRejected
+	case resp.StatusCode == http.StatusForbidden:
+		// Restricted keys without access to the account are still valid
+		return nil
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("unable to verify the API key: unexpected status %s", resp.Status)
+	default:
+		return nil
+	}
+}
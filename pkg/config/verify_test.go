@@ -0,0 +1,62 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/validators"
+)
+
+func TestVerifyAPIKeyOnline(t *testing.T) {
+	t.Setenv("STRIPE_API_KEY", "")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, _, _ := r.BasicAuth()
+
+		switch {
+		case r.URL.Path != "/v1/account":
+			w.WriteHeader(http.StatusNotFound)
+		case key == "sk_test_1234567890abcd":
+			w.Write([]byte(`{"id": "acct_123"}`))
+		case key == "rk_test_1234567890abcd":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	defer func(baseURL string) { verifyAPIBaseURL = baseURL }(verifyAPIBaseURL)
+	verifyAPIBaseURL = server.URL
+
+	ctx := context.Background()
+
+	for key, want := range map[string]error{
+		"sk_test_1234567890abcd": nil,
+		"rk_test_1234567890abcd": nil,
+		"sk_test_revoked890abcd": validators.ErrAPIKeyRejected,
+	} {
+		p := Profile{APIKey: key}
+		err := p.VerifyAPIKeyOnline(ctx, server.Client(), false)
+		if want == nil {
+			require.NoError(t, err, key)
+		} else {
+			require.ErrorIs(t, err, want, key)
+		}
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	p := Profile{APIKey: "sk_test_1234567890abcd"}
+	err := p.VerifyAPIKeyOnline(canceled, server.Client(), false)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The profile's client is used by default
+	err = p.VerifyAPIKeyOnline(ctx, nil, false)
+	require.NoError(t, err)
+}
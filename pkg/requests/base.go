@@ -128,6 +128,9 @@ func (rb *Base) RunRequestsCmd(cmd *cobra.Command, args []string) error {
 
 	apiKey, err := rb.Profile.GetAPIKey(rb.Livemode)
 	if err != nil {
+		if rb.Livemode && rb.Profile.LiveKeyNeedsRelogin() {
+			return fmt.Errorf("your live mode key isn't available on this machine, run `stripe login` to use it again")
+		}
 		return err
 	}
 
@@ -402,14 +405,24 @@ func (rb *Base) setIdempotencyHeader(request *http.Request, params *RequestParam
 }
 
 func (rb *Base) setVersionHeader(request *http.Request, params *RequestParameters) {
-	if params.version != "" {
-		request.Header.Set("Stripe-Version", params.version)
+	version := params.version
+	if version == "" && rb.Profile != nil {
+		version = rb.Profile.GetAPIVersion()
+	}
+
+	if version != "" {
+		request.Header.Set("Stripe-Version", version)
 	}
 }
 
 func (rb *Base) setStripeAccountHeader(request *http.Request, params *RequestParameters) {
-	if params.stripeAccount != "" {
-		request.Header.Set("Stripe-Account", params.stripeAccount)
+	stripeAccount := params.stripeAccount
+	if stripeAccount == "" && rb.Profile != nil {
+		stripeAccount = rb.Profile.GetDefaultConnectAccount()
+	}
+
+	if stripeAccount != "" {
+		request.Header.Set("Stripe-Account", stripeAccount)
 	}
 }
 
@@ -419,8 +432,15 @@ func (rb *Base) confirmCommand() (bool, error) {
 }
 
 func (rb *Base) getUserConfirmation(reader *bufio.Reader) (bool, error) {
-	if _, needsConfirmation := confirmationCommands[rb.Method]; needsConfirmation && !rb.autoConfirm {
-		confirmationPrompt := fmt.Sprintf("Are you sure you want to perform the command: %s?\nEnter 'yes' to confirm: ", rb.Method)
+	var confirmationPrompt string
+
+	if rb.Livemode && rb.Method != http.MethodGet && rb.Profile != nil && rb.Profile.RequiresLiveConfirmation() {
+		confirmationPrompt = fmt.Sprintf("You are about to run a live operation on %s. Continue?\nEnter 'yes' to confirm: ", rb.liveAccountName())
+	} else if _, needsConfirmation := confirmationCommands[rb.Method]; needsConfirmation {
+		confirmationPrompt = fmt.Sprintf("Are you sure you want to perform the command: %s?\nEnter 'yes' to confirm: ", rb.Method)
+	}
+
+	if confirmationPrompt != "" && !rb.autoConfirm {
 		fmt.Print(confirmationPrompt)
 
 		input, err := reader.ReadString('\n')
@@ -438,6 +458,16 @@ func (rb *Base) getUserConfirmation(reader *bufio.Reader) (bool, error) {
 	return true, nil
 }
 
+// liveAccountName returns how to refer to the account of the profile in the
+// live mode confirmation prompt.
+func (rb *Base) liveAccountName() string {
+	if name := rb.Profile.GetDisplayName(); name != "" {
+		return name
+	}
+
+	return fmt.Sprintf("the %s profile", rb.Profile.ProfileName)
+}
+
 func createOrNormalizePath(arg string) (string, error) {
 	if idRegex.Match([]byte(arg)) {
 		matches := idRegex.FindStringSubmatch(arg)
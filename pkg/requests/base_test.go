@@ -11,7 +11,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
+
+	"github.com/stripe/stripe-cli/pkg/config"
 )
 
 func TestBuildDataForRequest(t *testing.T) {
@@ -113,6 +116,50 @@ func TestMakeRequest(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestSetVersionHeader(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	rb := Base{Profile: &config.Profile{ProfileName: "tests"}}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rb.setVersionHeader(req, &RequestParameters{})
+	require.Empty(t, req.Header.Get("Stripe-Version"))
+
+	viper.Set("tests."+config.APIVersionName, "2020-08-27")
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rb.setVersionHeader(req, &RequestParameters{})
+	require.Equal(t, "2020-08-27", req.Header.Get("Stripe-Version"))
+
+	// The --stripe-version flag wins over the pinned version
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rb.setVersionHeader(req, &RequestParameters{version: "2022-08-01"})
+	require.Equal(t, "2022-08-01", req.Header.Get("Stripe-Version"))
+}
+
+func TestSetStripeAccountHeader(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	rb := Base{Profile: &config.Profile{ProfileName: "tests"}}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rb.setStripeAccountHeader(req, &RequestParameters{})
+	require.Empty(t, req.Header.Get("Stripe-Account"))
+
+	viper.Set("tests."+config.DefaultConnectAccountName, "acct_default")
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rb.setStripeAccountHeader(req, &RequestParameters{})
+	require.Equal(t, "acct_default", req.Header.Get("Stripe-Account"))
+
+	// The --stripe-account flag wins over the default account
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rb.setStripeAccountHeader(req, &RequestParameters{stripeAccount: "acct_flag"})
+	require.Equal(t, "acct_flag", req.Header.Get("Stripe-Account"))
+}
+
 func TestMakeRequest_ErrOnStatus(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -216,6 +263,40 @@ func TestGetUserConfirmationNotRequired(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestGetUserConfirmationLiveWrites(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	rb := Base{Profile: &config.Profile{ProfileName: "tests"}, Livemode: true}
+	rb.Method = http.MethodPost
+
+	confirmed, err := rb.getUserConfirmation(bufio.NewReader(strings.NewReader("")))
+	require.True(t, confirmed)
+	require.NoError(t, err)
+
+	viper.Set("tests."+config.ConfirmLiveWritesName, true)
+
+	confirmed, err = rb.getUserConfirmation(bufio.NewReader(strings.NewReader("no\n")))
+	require.False(t, confirmed)
+	require.NoError(t, err)
+
+	confirmed, err = rb.getUserConfirmation(bufio.NewReader(strings.NewReader("yes\n")))
+	require.True(t, confirmed)
+	require.NoError(t, err)
+
+	// Reads and test mode writes don't need a confirmation
+	rb.Method = http.MethodGet
+	confirmed, err = rb.getUserConfirmation(bufio.NewReader(strings.NewReader("")))
+	require.True(t, confirmed)
+	require.NoError(t, err)
+
+	rb.Method = http.MethodPost
+	rb.Livemode = false
+	confirmed, err = rb.getUserConfirmation(bufio.NewReader(strings.NewReader("")))
+	require.True(t, confirmed)
+	require.NoError(t, err)
+}
+
 func TestGetUserConfirmationAutoConfirm(t *testing.T) {
 	reader := bufio.NewReader(strings.NewReader(""))
 
@@ -0,0 +1,62 @@
+package serve
+
+import (
+	"os"
+	"sync"
+)
+
+// AccessLog is an access log file that can be reopened, so that it can be
+// rotated by moving it away and asking the server to reopen it.
+type AccessLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// OpenAccessLog opens the access log file at path for appending, creating it
+// if it doesn't exist.
+func OpenAccessLog(path string) (*AccessLog, error) {
+	l := &AccessLog{path: path}
+
+	err := l.Reopen()
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Write appends p to the log file
+func (l *AccessLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Write(p)
+}
+
+// Reopen closes the log file and opens the file at its path again. The
+// current file is kept if the new one can't be opened.
+func (l *AccessLog) Reopen() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644) // #nosec G302 -- access logs aren't secret
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.file = file
+
+	return nil
+}
+
+// Close closes the log file
+func (l *AccessLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
@@ -0,0 +1,36 @@
+package serve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0600))
+
+	l, err := OpenAccessLog(path)
+	require.NoError(t, err)
+	defer l.Close()
+
+	_, err = l.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "first\nsecond\n", string(contents))
+
+	// Rotate the file away, then reopen it
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, l.Reopen())
+
+	_, err = l.Write([]byte("third\n"))
+	require.NoError(t, err)
+
+	contents, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "third\n", string(contents))
+}
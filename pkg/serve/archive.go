@@ -0,0 +1,91 @@
+package serve
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing/fstest"
+)
+
+// OpenTar reads the tarball at name, optionally gzipped, into memory and
+// returns its contents as a filesystem. Only regular files and directories
+// are kept.
+func OpenTar(name string) (fs.FS, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+
+	// gzip streams start with the 0x1f 0x8b magic bytes
+	if magic, err := r.(*bufio.Reader).Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		r = gz
+	}
+
+	// MapFS is a plain map of paths to file contents, which is all we need
+	// for an in-memory filesystem. Parent directories are synthesized.
+	files := fstest.MapFS{}
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := archivePath(header.Name)
+		if !ok {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			files[name] = &fstest.MapFile{
+				Mode:    fs.ModeDir | fs.FileMode(header.Mode).Perm(),
+				ModTime: header.ModTime,
+			}
+		case tar.TypeReg:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+
+			files[name] = &fstest.MapFile{
+				Data:    data,
+				Mode:    fs.FileMode(header.Mode).Perm(),
+				ModTime: header.ModTime,
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// archivePath converts the name of an archive entry to a valid fs.FS path.
+// It returns false for entries that would end up outside of the archive root
+// or for the root itself.
+func archivePath(name string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") || !fs.ValidPath(name) {
+		return "", false
+	}
+
+	return name, true
+}
@@ -0,0 +1,60 @@
+package serve
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestTar(t *testing.T, gzipped bool) string {
+	name := filepath.Join(t.TempDir(), "build.tar")
+	f, err := os.Create(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var tw *tar.Writer
+	if gzipped {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		tw = tar.NewWriter(gz)
+	} else {
+		tw = tar.NewWriter(f)
+	}
+	defer tw.Close()
+
+	for name, contents := range map[string]string{
+		"./dist/index.html":    "<p>hello</p>",
+		"./dist/js/app.js":     "alert(1)",
+		"../../etc/passwd":     "nope",
+		"dist/js/../style.css": "p {}",
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err = tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+
+	return name
+}
+
+func TestOpenTar(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		fsys, err := OpenTar(writeTestTar(t, gzipped))
+		require.NoError(t, err)
+
+		data, err := fs.ReadFile(fsys, "dist/index.html")
+		require.NoError(t, err)
+		require.Equal(t, "<p>hello</p>", string(data))
+
+		entries, err := fs.ReadDir(fsys, "dist")
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+
+		_, err = fs.Stat(fsys, "etc/passwd")
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,109 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bandwidthUnits are the units accepted by ParseBandwidth, in bytes
+var bandwidthUnits = map[string]int64{
+	"b":  1,
+	"kb": 1024,
+	"mb": 1024 * 1024,
+}
+
+// maxThrottledChunk is the largest write a throttled response makes at once
+const maxThrottledChunk = 32 * 1024
+
+// ParseBandwidth parses a bandwidth formatted as <amount><unit>/s, where unit
+// is one of b, kb or mb, e.g. 100kb/s. It returns the number of bytes per
+// second.
+func ParseBandwidth(value string) (int64, error) {
+	amount := strings.ToLower(value)
+	if !strings.HasSuffix(amount, "/s") {
+		return 0, fmt.Errorf("invalid bandwidth %q, expected a value such as 100kb/s", value)
+	}
+
+	amount = strings.TrimSuffix(amount, "/s")
+
+	digits := strings.TrimRight(amount, "abkm")
+	multiplier, ok := bandwidthUnits[amount[len(digits):]]
+	if !ok {
+		return 0, fmt.Errorf("invalid bandwidth %q, the unit must be one of b, kb, mb", value)
+	}
+
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q, the amount must be a positive integer", value)
+	}
+
+	return n * multiplier, nil
+}
+
+// withBandwidth limits the speed at which response bodies are written to
+// bytesPerSecond. Writes are abandoned when the client goes away.
+func withBandwidth(next http.Handler, bytesPerSecond int64) http.Handler {
+	chunk := bytesPerSecond / 10
+	if chunk < 1 {
+		chunk = 1
+	} else if chunk > maxThrottledChunk {
+		chunk = maxThrottledChunk
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&throttledWriter{
+			ResponseWriter: w,
+			r:              r,
+			rate:           bytesPerSecond,
+			chunk:          int(chunk),
+			start:          time.Now(),
+		}, r)
+	})
+}
+
+// throttledWriter writes the response body in chunks, sleeping between them
+// so that the average speed stays under rate bytes per second.
+type throttledWriter struct {
+	http.ResponseWriter
+
+	r       *http.Request
+	rate    int64
+	chunk   int
+	start   time.Time
+	written int64
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	var n int
+
+	for len(p) > 0 {
+		size := w.chunk
+		if size > len(p) {
+			size = len(p)
+		}
+
+		// Wait until the bytes already sent fit in the rate
+		due := w.start.Add(time.Duration(w.written * int64(time.Second) / w.rate))
+		if wait := time.Until(due); wait > 0 && !sleep(w.r.Context(), wait) {
+			return n, w.r.Context().Err()
+		}
+
+		written, err := w.ResponseWriter.Write(p[:size])
+		n += written
+		w.written += int64(written)
+		if err != nil {
+			return n, err
+		}
+
+		if f, ok := w.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		p = p[size:]
+	}
+
+	return n, nil
+}
@@ -0,0 +1,62 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	for value, want := range map[string]int64{
+		"500b/s":  500,
+		"100kb/s": 100 * 1024,
+		"2MB/s":   2 * 1024 * 1024,
+	} {
+		got, err := ParseBandwidth(value)
+		require.NoError(t, err, value)
+		require.Equal(t, want, got, value)
+	}
+
+	for _, value := range []string{"", "100kb", "100/s", "0kb/s", "-1kb/s", "1.5mb/s", "100gb/s", "kb/s"} {
+		_, err := ParseBandwidth(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestWithBandwidth(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 3000)
+	handler := withBandwidth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}), 10000)
+
+	start := time.Now()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// The last 1000 bytes chunk is sent without waiting at 0.2s
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	require.Equal(t, body, rr.Body.Bytes())
+}
+
+func TestWithBandwidthCanceled(t *testing.T) {
+	var writeErr error
+	handler := withBandwidth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, writeErr = w.Write(bytes.Repeat([]byte("a"), 1000))
+	}), 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	require.ErrorIs(t, writeErr, context.Canceled)
+	require.Less(t, time.Since(start), time.Second)
+	require.Less(t, rr.Body.Len(), 1000)
+}
@@ -0,0 +1,69 @@
+package serve
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// ParseBaseHref checks that value is an absolute path to inject as the href
+// of a <base> tag, and adds the trailing slash without which browsers would
+// resolve relative URLs from its parent directory.
+func ParseBaseHref(value string) (string, error) {
+	if !strings.HasPrefix(value, "/") || strings.HasPrefix(value, "//") {
+		return "", fmt.Errorf("invalid base path %q, expected an absolute path such as /app/", value)
+	}
+
+	if !strings.HasSuffix(value, "/") {
+		value += "/"
+	}
+
+	return value, nil
+}
+
+// injectBase returns a rewrite func adding a <base> tag with the given href
+// at the start of the <head> of HTML documents. Documents without a <head>,
+// or that already have a <base> tag, are left untouched.
+func injectBase(href string) func([]byte) []byte {
+	tag := []byte(`<base href="` + html.EscapeString(href) + `">`)
+
+	return func(body []byte) []byte {
+		lower := bytes.ToLower(body)
+		if bytes.Contains(lower, []byte("<base ")) {
+			return body
+		}
+
+		end := headTagEnd(lower)
+		if end < 0 {
+			return body
+		}
+
+		rewritten := make([]byte, 0, len(body)+len(tag))
+		rewritten = append(rewritten, body[:end]...)
+		rewritten = append(rewritten, tag...)
+		return append(rewritten, body[end:]...)
+	}
+}
+
+// headTagEnd returns the index right after the opening <head> tag found in
+// the lowercased document, or -1 if it has none.
+func headTagEnd(lower []byte) int {
+	for offset := 0; ; {
+		i := bytes.Index(lower[offset:], []byte("<head"))
+		if i < 0 {
+			return -1
+		}
+		i += offset + len("<head")
+
+		// Don't mistake <header> for <head>
+		if i < len(lower) && (lower[i] == '>' || lower[i] == ' ' || lower[i] == '\t' || lower[i] == '\n' || lower[i] == '\r') {
+			if j := bytes.IndexByte(lower[i:], '>'); j >= 0 {
+				return i + j + 1
+			}
+			return -1
+		}
+
+		offset = i
+	}
+}
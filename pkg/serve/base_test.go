@@ -0,0 +1,66 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBaseHref(t *testing.T) {
+	href, err := ParseBaseHref("/app")
+	require.NoError(t, err)
+	require.Equal(t, "/app/", href)
+
+	href, err = ParseBaseHref("/app/")
+	require.NoError(t, err)
+	require.Equal(t, "/app/", href)
+
+	for _, value := range []string{"", "app/", "//example.com/", "https://example.com/"} {
+		_, err := ParseBaseHref(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestInjectBase(t *testing.T) {
+	rewrite := injectBase("/app/")
+
+	require.Equal(t,
+		`<html><HEAD lang="en"><base href="/app/"><title>x</title></HEAD></html>`,
+		string(rewrite([]byte(`<html><HEAD lang="en"><title>x</title></HEAD></html>`))),
+	)
+	require.Equal(t,
+		`<body><header></header></body>`,
+		string(rewrite([]byte(`<body><header></header></body>`))),
+	)
+	require.Equal(t,
+		`<header></header><head><base href="/app/"></head>`,
+		string(rewrite([]byte(`<header></header><head></head>`))),
+	)
+	require.Equal(t,
+		`<head><base href="/other/"></head>`,
+		string(rewrite([]byte(`<head><base href="/other/"></head>`))),
+	)
+	require.Equal(t,
+		`<head><base href="/a&#34;b/"></head>`,
+		string(injectBase(`/a"b/`)([]byte(`<head></head>`))),
+	)
+}
+
+func TestHandlerInjectBase(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html><head></head></html>"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("<head></head>"), 0600))
+	handler := NewHandler(http.Dir(dir), &Config{BaseHref: "/app/"})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, `<html><head><base href="/app/"></head></html>`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	require.Equal(t, "<head></head>", rr.Body.String())
+}
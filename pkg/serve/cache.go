@@ -0,0 +1,151 @@
+package serve
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+)
+
+// limits of the file cache enabled with Config.CacheFiles
+const (
+	cacheMaxFileSize  = 1 << 20
+	cacheMaxTotalSize = 64 << 20
+)
+
+// cachedFS keeps the contents of small files in memory, so that they don't
+// need to be read again from a slow disk on every request. Files are still
+// stat'ed on every open, and cached contents are dropped when the
+// modification time or size of the file changes. The least recently used
+// files are evicted once the cache holds more than maxTotalSize bytes.
+type cachedFS struct {
+	fs           http.FileSystem
+	maxFileSize  int64
+	maxTotalSize int64
+
+	mu        sync.Mutex
+	lru       *list.List
+	entries   map[string]*list.Element
+	totalSize int64
+}
+
+type cacheEntry struct {
+	name string
+	info os.FileInfo
+	data []byte
+}
+
+func newCachedFS(fs http.FileSystem, maxFileSize, maxTotalSize int64) *cachedFS {
+	return &cachedFS{
+		fs:           fs,
+		maxFileSize:  maxFileSize,
+		maxTotalSize: maxTotalSize,
+		lru:          list.New(),
+		entries:      make(map[string]*list.Element),
+	}
+}
+
+// Open opens name, from the cache when its contents are cached and up to date
+func (c *cachedFS) Open(name string) (http.File, error) {
+	name = path.Clean("/" + name)
+
+	f, err := c.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() || info.Size() > c.maxFileSize {
+		return f, nil
+	}
+
+	if entry := c.get(name, info); entry != nil {
+		f.Close()
+		return newMemFile(entry), nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, c.maxFileSize+1))
+	f.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The file changed while we were reading it
+	if int64(len(data)) != info.Size() {
+		return c.fs.Open(name)
+	}
+
+	entry := &cacheEntry{name: name, info: info, data: data}
+	c.put(entry)
+
+	return newMemFile(entry), nil
+}
+
+// get returns the cached entry for name if it matches info.
+func (c *cachedFS) get(name string, info os.FileInfo) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[name]
+	if !ok {
+		return nil
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !entry.info.ModTime().Equal(info.ModTime()) || entry.info.Size() != info.Size() {
+		c.remove(elem)
+		return nil
+	}
+
+	c.lru.MoveToFront(elem)
+
+	return entry
+}
+
+func (c *cachedFS) put(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[entry.name]; ok {
+		c.remove(elem)
+	}
+
+	c.entries[entry.name] = c.lru.PushFront(entry)
+	c.totalSize += int64(len(entry.data))
+
+	for c.totalSize > c.maxTotalSize {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *cachedFS) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.name)
+	c.totalSize -= int64(len(entry.data))
+}
+
+// memFile is an http.File reading the cached contents of a file
+type memFile struct {
+	*bytes.Reader
+	info os.FileInfo
+}
+
+func newMemFile(entry *cacheEntry) *memFile {
+	return &memFile{Reader: bytes.NewReader(entry.data), info: entry.info}
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
+
+func (f *memFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, &os.PathError{Op: "readdir", Path: f.info.Name(), Err: os.ErrInvalid}
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
@@ -0,0 +1,96 @@
+package serve
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readCountFS counts the files read from the wrapped filesystem
+type readCountFS struct {
+	http.FileSystem
+	reads map[string]int
+}
+
+type readCountFile struct {
+	http.File
+	count func()
+}
+
+func (f *readCountFile) Read(p []byte) (int, error) {
+	f.count()
+	return f.File.Read(p)
+}
+
+func (fs *readCountFS) Open(name string) (http.File, error) {
+	f, err := fs.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	counted := false
+	return &readCountFile{File: f, count: func() {
+		if !counted {
+			counted = true
+			fs.reads[name]++
+		}
+	}}, nil
+}
+
+func TestCachedFS(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string, modTime time.Time) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	disk := &readCountFS{FileSystem: http.Dir(dir), reads: make(map[string]int)}
+	fs := newCachedFS(disk, 8, 8)
+
+	read := func(name string) string {
+		f, err := fs.Open(name)
+		require.NoError(t, err)
+		defer f.Close()
+
+		contents, err := io.ReadAll(f)
+		require.NoError(t, err)
+
+		return string(contents)
+	}
+
+	modTime := time.Now().Add(-time.Hour)
+	write("a.js", "aaaa", modTime)
+	write("b.js", "bbbb", modTime)
+	write("c.js", "cccc", modTime)
+	write("big.js", "0123456789", modTime)
+
+	require.Equal(t, "aaaa", read("/a.js"))
+	require.Equal(t, "aaaa", read("/a.js"))
+	require.Equal(t, 1, disk.reads["/a.js"])
+
+	// Files above the size threshold are never cached
+	read("/big.js")
+	read("/big.js")
+	require.Equal(t, 2, disk.reads["/big.js"])
+
+	// Changing the file invalidates the cache
+	write("a.js", "AAAA", modTime.Add(time.Minute))
+	require.Equal(t, "AAAA", read("/a.js"))
+	require.Equal(t, 2, disk.reads["/a.js"])
+
+	// a.js is the least recently used once b.js and c.js are read
+	read("/b.js")
+	read("/c.js")
+	require.Equal(t, int64(8), fs.totalSize)
+
+	read("/b.js")
+	require.Equal(t, 1, disk.reads["/b.js"])
+	read("/a.js")
+	require.Equal(t, 3, disk.reads["/a.js"])
+}
@@ -0,0 +1,122 @@
+package serve
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// certCheckInterval is how often the certificate files are checked for
+// changes, at most
+const certCheckInterval = time.Second
+
+// CertReloader serves a certificate loaded from PEM files, and reloads it
+// when the files change so that new connections use the new certificate
+// without restarting the server.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certInfo  os.FileInfo
+	keyInfo   os.FileInfo
+	checkedAt time.Time
+	interval  time.Duration
+}
+
+// NewCertReloader loads the certificate in certFile along with its private key
+// in keyFile.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	c := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		interval: certCheckInterval,
+	}
+
+	err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// load reads the certificate files, and must be called with mu held
+func (c *CertReloader) load() error {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return err
+	}
+
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	c.cert = &cert
+	c.certInfo = certInfo
+	c.keyInfo = keyInfo
+
+	return nil
+}
+
+// changed returns whether the certificate files were modified since they were
+// loaded, and must be called with mu held
+func (c *CertReloader) changed() bool {
+	for _, f := range []struct {
+		path string
+		info os.FileInfo
+	}{
+		{c.certFile, c.certInfo},
+		{c.keyFile, c.keyInfo},
+	} {
+		info, err := os.Stat(f.path)
+		if err != nil {
+			// The file may be in the middle of being replaced
+			return false
+		}
+
+		if !info.ModTime().Equal(f.info.ModTime()) || info.Size() != f.info.Size() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetCertificate returns the current certificate, reloading it first if its
+// files changed. It's meant to be used as tls.Config.GetCertificate. When the
+// new files can't be loaded, e.g. because only one of them was written yet,
+// the previous certificate keeps being served.
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.checkedAt) < c.interval {
+		return c.cert, nil
+	}
+	c.checkedAt = time.Now()
+
+	if c.changed() {
+		if err := c.load(); err != nil {
+			log.WithFields(log.Fields{
+				"prefix": "serve.CertReloader.GetCertificate",
+			}).Debugf("Failed to reload the certificate, keeping the previous one: %v", err)
+		} else {
+			log.WithFields(log.Fields{
+				"prefix": "serve.CertReloader.GetCertificate",
+			}).Debug("Reloaded the certificate")
+		}
+	}
+
+	return c.cert, nil
+}
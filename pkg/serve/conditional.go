@@ -0,0 +1,18 @@
+package serve
+
+import (
+	"net/http"
+)
+
+// withoutConditionals makes next ignore the If-Modified-Since and
+// If-None-Match headers of requests, so that files are always served in full
+// instead of with a 304 Not Modified, even when their modification time
+// doesn't change after an edit.
+func withoutConditionals(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("If-Modified-Since")
+		r.Header.Del("If-None-Match")
+
+		next.ServeHTTP(w, r)
+	})
+}
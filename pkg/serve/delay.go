@@ -0,0 +1,86 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PathDelay is an extra delay applied to the requests whose path starts with
+// Prefix
+type PathDelay struct {
+	Prefix string
+	Delay  time.Duration
+}
+
+// ParsePathDelay parses a path delay formatted as <prefix>=<duration>, e.g.
+// /slow=2s.
+func ParsePathDelay(value string) (PathDelay, error) {
+	prefix, duration, ok := strings.Cut(value, "=")
+	if !ok || !strings.HasPrefix(prefix, "/") {
+		return PathDelay{}, fmt.Errorf("invalid path delay %q, expected a value such as /slow=2s", value)
+	}
+
+	delay, err := time.ParseDuration(duration)
+	if err != nil || delay < 0 {
+		return PathDelay{}, fmt.Errorf("invalid path delay %q, the delay must be a positive duration such as 2s", value)
+	}
+
+	return PathDelay{Prefix: prefix, Delay: delay}, nil
+}
+
+// withDelay sleeps for delay plus a random jitter before handing the request
+// to next. If the client goes away while we're sleeping, the request is
+// abandoned without being served.
+func withDelay(next http.Handler, delay, jitter time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := delay
+		if jitter > 0 {
+			d += time.Duration(rand.Int63n(int64(jitter))) // #nosec G404 -- not used for anything security related
+		}
+
+		if sleep(r.Context(), d) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// withPathDelays sleeps for the delay of the longest prefix matching the
+// request path before handing the request to next, on top of any delay
+// applied by withDelay.
+func withPathDelays(next http.Handler, delays []PathDelay) http.Handler {
+	delays = append([]PathDelay(nil), delays...)
+	sort.SliceStable(delays, func(i, j int) bool {
+		return len(delays[i].Prefix) > len(delays[j].Prefix)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, delay := range delays {
+			if strings.HasPrefix(r.URL.Path, delay.Prefix) {
+				if !sleep(r.Context(), delay.Delay) {
+					return
+				}
+				break
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sleep waits for d, and returns false if ctx is done before then.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,73 @@
+package serve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDelay(t *testing.T) {
+	called := false
+	handler := withDelay(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}), 50*time.Millisecond, 0)
+
+	start := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.True(t, called)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestWithDelayCanceled(t *testing.T) {
+	called := false
+	handler := withDelay(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}), time.Hour, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.False(t, called)
+}
+
+func TestParsePathDelay(t *testing.T) {
+	delay, err := ParsePathDelay("/slow=2s")
+	require.NoError(t, err)
+	require.Equal(t, PathDelay{Prefix: "/slow", Delay: 2 * time.Second}, delay)
+
+	for _, value := range []string{"/slow", "slow=2s", "/slow=soon", "/slow=-1s"} {
+		_, err = ParsePathDelay(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestWithPathDelays(t *testing.T) {
+	handler := withPathDelays(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), []PathDelay{
+		{Prefix: "/api", Delay: time.Hour},
+		{Prefix: "/api/fast", Delay: 0},
+	})
+
+	start := time.Now()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/fast/users", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	require.Less(t, time.Since(start), time.Second)
+
+	called := false
+	handler = withPathDelays(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}), []PathDelay{{Prefix: "/api", Delay: time.Hour}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/users", nil).WithContext(ctx))
+	require.False(t, called)
+}
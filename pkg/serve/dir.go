@@ -0,0 +1,127 @@
+package serve
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrDirUnavailable is returned when the served directory doesn't exist
+// anymore, e.g. while a build tool is recreating it
+var ErrDirUnavailable = errors.New("served directory is currently unavailable")
+
+// DirWrapper is an http.Dir that detects its root directory being removed
+// and recreated while it's served.
+//
+// It also guarantees that no file outside of the root directory is ever
+// opened: names with .. components, and symbolic links resolving to a path
+// outside of the root, fail with a permission error, which http.FileServer
+// turns into a 403 Forbidden.
+type DirWrapper struct {
+	dir  http.Dir
+	root string
+
+	mu          sync.Mutex
+	unavailable bool
+}
+
+// NewDirWrapper returns a DirWrapper serving the files in root
+func NewDirWrapper(root string) *DirWrapper {
+	return &DirWrapper{dir: http.Dir(root), root: root}
+}
+
+// Open opens name like http.Dir, but returns ErrDirUnavailable if the root
+// directory doesn't exist, and a permission error if name is outside of it.
+func (d *DirWrapper) Open(name string) (http.File, error) {
+	if !d.Available() {
+		return nil, ErrDirUnavailable
+	}
+
+	if !d.confined(name) {
+		log.WithFields(log.Fields{
+			"prefix": "serve.DirWrapper.Open",
+			"path":   name,
+		}).Warn("Refused to open a path outside of the served directory")
+
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+	}
+
+	return d.dir.Open(name)
+}
+
+// confined returns whether name, once symbolic links are resolved, is in the
+// root directory. Names that don't exist are confined, opening them fails
+// anyway.
+func (d *DirWrapper) confined(name string) bool {
+	if strings.ContainsRune(name, 0) {
+		return false
+	}
+
+	for _, elem := range strings.Split(filepath.ToSlash(name), "/") {
+		if elem == ".." {
+			return false
+		}
+	}
+
+	root, err := filepath.EvalSymlinks(d.root)
+	if err != nil {
+		return false
+	}
+
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(path.Clean("/"+name))))
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Available returns whether the root directory exists. Changes are logged, so
+// that it's clear why requests fail while the directory is missing.
+func (d *DirWrapper) Available() bool {
+	info, err := os.Stat(d.root)
+	available := err == nil && info.IsDir()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if available == !d.unavailable {
+		return available
+	}
+	d.unavailable = !available
+
+	logger := log.WithFields(log.Fields{
+		"prefix": "serve.DirWrapper.Available",
+		"dir":    d.root,
+	})
+
+	if available {
+		logger.Info("Served directory is available again")
+	} else {
+		logger.Warn("Served directory is unavailable, responding with 503 until it's recreated")
+	}
+
+	return available
+}
+
+// withAvailability responds with a 503 Service Unavailable instead of calling
+// next while available returns false.
+func withAvailability(next http.Handler, available func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available() {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, ErrDirUnavailable.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,100 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirWrapper(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "dist")
+	require.NoError(t, os.Mkdir(root, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.js"), []byte("v1"), 0600))
+
+	dir := NewDirWrapper(root)
+	handler := NewHandler(dir, &Config{Available: dir.Available})
+
+	get := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+		return rr
+	}
+
+	rr := get()
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "v1", rr.Body.String())
+
+	require.NoError(t, os.RemoveAll(root))
+
+	rr = get()
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	require.Equal(t, "1", rr.Header().Get("Retry-After"))
+	require.Contains(t, rr.Body.String(), "served directory is currently unavailable")
+
+	_, err := dir.Open("/app.js")
+	require.ErrorIs(t, err, ErrDirUnavailable)
+
+	require.NoError(t, os.Mkdir(root, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.js"), []byte("v2"), 0600))
+
+	rr = get()
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "v2", rr.Body.String())
+}
+
+func TestDirWrapperConfinement(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "dist")
+	require.NoError(t, os.Mkdir(root, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app.js"), []byte("render()"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("hunter2"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(parent, "private"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(parent, "private", "key.pem"), []byte("hunter2"), 0600))
+	require.NoError(t, os.Symlink(filepath.Join(parent, "secret.txt"), filepath.Join(root, "secret.txt")))
+	require.NoError(t, os.Symlink(filepath.Join(parent, "private"), filepath.Join(root, "private")))
+	require.NoError(t, os.Symlink("app.js", filepath.Join(root, "link.js")))
+
+	dir := NewDirWrapper(root)
+
+	for _, name := range []string{"/../secret.txt", "../../etc/passwd", "/js/../../secret.txt", "/secret.txt", "/private/key.pem", "/app.js\x00"} {
+		_, err := dir.Open(name)
+		require.True(t, os.IsPermission(err), name)
+	}
+
+	f, err := dir.Open("/link.js")
+	require.NoError(t, err)
+	f.Close()
+
+	_, err = dir.Open("/missing.js")
+	require.True(t, os.IsNotExist(err))
+
+	handler := NewHandler(dir, &Config{Available: dir.Available})
+
+	for _, target := range []string{
+		"/../secret.txt",
+		"/%2e%2e/secret.txt",
+		"/%2e%2e%2fsecret.txt",
+		"/..%2f..%2fetc%2fpasswd",
+		"/secret.txt",
+		"/private/key.pem",
+		"/private/",
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		require.NotEqual(t, http.StatusOK, rr.Code, target)
+		require.NotContains(t, rr.Body.String(), "hunter2", target)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/secret.txt", nil))
+	require.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/link.js", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "render()", rr.Body.String())
+}
@@ -0,0 +1,35 @@
+package serve
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// withDownloads sets the Content-Disposition header of files so that browsers
+// download them instead of displaying them. All files are downloaded when
+// extensions is empty, otherwise only those with one of the extensions.
+func withDownloads(next http.Handler, fs http.FileSystem, extensions []string) http.Handler {
+	exts := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		exts["."+strings.ToLower(strings.TrimPrefix(ext, "."))] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+
+		if len(exts) == 0 || exts[strings.ToLower(path.Ext(name))] {
+			if f, err := fs.Open(name); err == nil {
+				info, err := f.Stat()
+				f.Close()
+
+				if err == nil && !info.IsDir() {
+					w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(name)}))
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,34 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDownloads(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.PDF"), []byte("%PDF"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>hi</p>"), 0600))
+
+	fs := http.Dir(dir)
+
+	get := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	handler := withDownloads(http.FileServer(fs), fs, []string{"pdf", ".zip"})
+	require.Equal(t, `attachment; filename=report.PDF`, get(handler, "/report.PDF").Header().Get("Content-Disposition"))
+	require.Empty(t, get(handler, "/index.html").Header().Get("Content-Disposition"))
+	require.Empty(t, get(handler, "/missing.pdf").Header().Get("Content-Disposition"))
+
+	handler = withDownloads(http.FileServer(fs), fs, nil)
+	require.Equal(t, `attachment; filename=index.html`, get(handler, "/index.html").Header().Get("Content-Disposition"))
+	require.Empty(t, get(handler, "/").Header().Get("Content-Disposition"))
+}
@@ -0,0 +1,134 @@
+package serve
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// interceptWriter discards the response written by a handler when its status
+// matches intercept, so that a different response can be written instead.
+type interceptWriter struct {
+	http.ResponseWriter
+
+	intercept   func(status int) bool
+	status      int
+	intercepted bool
+	wroteHeader bool
+}
+
+func (w *interceptWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if w.intercept(code) {
+		w.status = code
+		w.intercepted = true
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *interceptWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.intercepted {
+		return len(p), nil
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func isServerError(status int) bool {
+	return status >= 500
+}
+
+// withVerboseErrors replaces the body of 5xx responses with the underlying
+// error and the requested path, and logs them.
+func withVerboseErrors(next http.Handler, fs http.FileSystem) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		iw := &interceptWriter{ResponseWriter: w, intercept: isServerError}
+		next.ServeHTTP(iw, r)
+
+		if !iw.intercepted {
+			return
+		}
+
+		name := path.Clean("/" + r.URL.Path)
+		err := diagnoseFile(fs, name)
+
+		log.WithFields(log.Fields{
+			"prefix": "serve.withVerboseErrors",
+			"path":   name,
+		}).Errorf("Failed to serve file: %v", err)
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(iw.status)
+		fmt.Fprintf(w, "%d %s\n\npath: %s\nerror: %v\n", iw.status, http.StatusText(iw.status), name, err)
+	})
+}
+
+func isInternalServerError(status int) bool {
+	return status == http.StatusInternalServerError
+}
+
+// withErrorPage replaces the body of 500 responses with page, an HTML
+// document.
+func withErrorPage(next http.Handler, page []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		iw := &interceptWriter{ResponseWriter: w, intercept: isInternalServerError}
+		next.ServeHTTP(iw, r)
+
+		if !iw.intercepted {
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+		w.WriteHeader(iw.status)
+
+		if r.Method != http.MethodHead {
+			w.Write(page)
+		}
+	})
+}
+
+// diagnoseFile retries the operations http.FileServer performs on name to
+// find the error that made it fail.
+func diagnoseFile(fs http.FileSystem, name string) error {
+	f, err := fs.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		_, err = f.Readdir(-1)
+	} else {
+		_, err = io.CopyN(io.Discard, f, 512)
+		if err == io.EOF {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("unknown error")
+}
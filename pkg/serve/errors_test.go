@@ -0,0 +1,69 @@
+package serve
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failingFS fails to open files that aren't missing, with an error that
+// http.FileServer reports as a 500
+type failingFS struct{}
+
+func (failingFS) Open(name string) (http.File, error) {
+	return nil, errors.New("input/output error")
+}
+
+func TestWithVerboseErrors(t *testing.T) {
+	var fs http.FileSystem = failingFS{}
+	handler := withVerboseErrors(http.FileServer(fs), fs)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/secret/file.txt", nil))
+
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.Equal(t, "500 Internal Server Error\n\npath: /secret/file.txt\nerror: input/output error\n", rr.Body.String())
+}
+
+func TestWithVerboseErrorsPassesThrough(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(dir+"/index.html", []byte("hello"), 0600))
+
+	fs := http.Dir(dir)
+	handler := withVerboseErrors(http.FileServer(fs), fs)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/missing.txt", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.True(t, strings.HasPrefix(rr.Body.String(), "404"))
+}
+
+func TestWithErrorPage(t *testing.T) {
+	page := []byte("<h1>Something went wrong</h1>")
+	handler := withErrorPage(http.FileServer(failingFS{}), page)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/secret/file.txt", nil))
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	require.Equal(t, string(page), rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodHead, "/secret/file.txt", nil))
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.Empty(t, rr.Body.String())
+}
+
+func TestWithErrorPagePassesThrough(t *testing.T) {
+	handler := withErrorPage(http.FileServer(http.Dir(t.TempDir())), []byte("<h1>Something went wrong</h1>"))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/missing.txt", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.True(t, strings.HasPrefix(rr.Body.String(), "404"))
+}
@@ -0,0 +1,161 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/stripe/stripe-cli/pkg/version"
+)
+
+// HARRecorder records the requests served by a handler, to write them as an
+// HTTP Archive once the server is done. Only the request line, status, sizes
+// and timing are recorded: headers and bodies are left out to keep the file
+// small and free of secrets.
+type HARRecorder struct {
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// NewHARRecorder returns a HARRecorder with no entries
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// The subset of the HAR 1.2 format written by HARRecorder, see
+// http://www.softwareishard.com/blog/har-12-spec/
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string        `json:"method"`
+	URL         string        `json:"url"`
+	HTTPVersion string        `json:"httpVersion"`
+	Cookies     []interface{} `json:"cookies"`
+	Headers     []interface{} `json:"headers"`
+	QueryString []interface{} `json:"queryString"`
+	HeadersSize int           `json:"headersSize"`
+	BodySize    int64         `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int           `json:"status"`
+	StatusText  string        `json:"statusText"`
+	HTTPVersion string        `json:"httpVersion"`
+	Cookies     []interface{} `json:"cookies"`
+	Headers     []interface{} `json:"headers"`
+	Content     harContent    `json:"content"`
+	RedirectURL string        `json:"redirectURL"`
+	HeadersSize int           `json:"headersSize"`
+	BodySize    int64         `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// wrap records the requests served by next
+func (h *HARRecorder) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+
+		entry := harEntry{
+			StartedDateTime: start.UTC(),
+			Time:            elapsed,
+			Request: harRequest{
+				Method:      r.Method,
+				URL:         scheme + "://" + r.Host + r.URL.RequestURI(),
+				HTTPVersion: r.Proto,
+				Cookies:     []interface{}{},
+				Headers:     []interface{}{},
+				QueryString: []interface{}{},
+				HeadersSize: -1,
+				BodySize:    r.ContentLength,
+			},
+			Response: harResponse{
+				Status:      status,
+				StatusText:  http.StatusText(status),
+				HTTPVersion: r.Proto,
+				Cookies:     []interface{}{},
+				Headers:     []interface{}{},
+				Content: harContent{
+					Size:     sw.size,
+					MimeType: sw.Header().Get("Content-Type"),
+				},
+				RedirectURL: sw.Header().Get("Location"),
+				HeadersSize: -1,
+				BodySize:    sw.size,
+			},
+			Timings: harTimings{Wait: elapsed},
+		}
+
+		h.mu.Lock()
+		h.entries = append(h.entries, entry)
+		h.mu.Unlock()
+	})
+}
+
+// WriteFile writes the requests recorded so far to path as an HTTP Archive
+func (h *HARRecorder) WriteFile(path string) error {
+	h.mu.Lock()
+	entries := append([]harEntry{}, h.entries...)
+	h.mu.Unlock()
+
+	out, err := json.MarshalIndent(harFile{
+		Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "stripe-cli", Version: version.Version},
+			Entries: entries,
+		},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, out, 0600)
+}
@@ -0,0 +1,45 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHARRecorder(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log(1)"), 0600))
+
+	recorder := NewHARRecorder()
+	handler := NewHandler(http.Dir(dir), &Config{HAR: recorder})
+
+	for _, target := range []string{"/app.js?v=1", "/missing.css"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost:4242"+target, nil))
+	}
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	require.NoError(t, recorder.WriteFile(path))
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var har harFile
+	require.NoError(t, json.Unmarshal(contents, &har))
+	require.Equal(t, "1.2", har.Log.Version)
+	require.Len(t, har.Log.Entries, 2)
+
+	js := har.Log.Entries[0]
+	require.Equal(t, http.MethodGet, js.Request.Method)
+	require.Equal(t, "http://localhost:4242/app.js?v=1", js.Request.URL)
+	require.Equal(t, http.StatusOK, js.Response.Status)
+	require.Equal(t, int64(len("console.log(1)")), js.Response.Content.Size)
+	require.Contains(t, js.Response.Content.MimeType, "javascript")
+
+	require.Equal(t, http.StatusNotFound, har.Log.Entries[1].Response.Status)
+	require.Equal(t, "Not Found", har.Log.Entries[1].Response.StatusText)
+}
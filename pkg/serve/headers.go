@@ -0,0 +1,18 @@
+package serve
+
+import (
+	"net/http"
+)
+
+// withHeaders sets the given headers on every response.
+func withHeaders(next http.Handler, headers http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, values := range headers {
+			for _, value := range values {
+				w.Header().Add(name, value)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
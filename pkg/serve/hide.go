@@ -0,0 +1,65 @@
+package serve
+
+import (
+	"net/http"
+	"os"
+	"path"
+)
+
+// hiddenFS hides some files of a filesystem, so that they can't be downloaded
+// or seen in directory listings.
+type hiddenFS struct {
+	fs     http.FileSystem
+	hidden map[string]bool
+}
+
+// HideFiles returns a filesystem serving the files of fs except the given
+// paths, which are relative to its root. The hidden files are reported as not
+// existing and left out of directory listings.
+func HideFiles(fs http.FileSystem, paths []string) http.FileSystem {
+	if len(paths) == 0 {
+		return fs
+	}
+
+	hidden := make(map[string]bool, len(paths))
+	for _, name := range paths {
+		hidden[path.Clean("/"+name)] = true
+	}
+
+	return &hiddenFS{fs: fs, hidden: hidden}
+}
+
+// Open opens name unless it's hidden
+func (h *hiddenFS) Open(name string) (http.File, error) {
+	name = path.Clean("/" + name)
+	if h.hidden[name] {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	f, err := h.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hiddenDir{File: f, fs: h, dir: name}, nil
+}
+
+// hiddenDir leaves the hidden files out of the entries of a directory
+type hiddenDir struct {
+	http.File
+	fs  *hiddenFS
+	dir string
+}
+
+func (d *hiddenDir) Readdir(count int) ([]os.FileInfo, error) {
+	infos, err := d.File.Readdir(count)
+
+	visible := infos[:0]
+	for _, info := range infos {
+		if !d.fs.hidden[path.Join(d.dir, info.Name())] {
+			visible = append(visible, info)
+		}
+	}
+
+	return visible, err
+}
@@ -0,0 +1,43 @@
+package serve
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHideFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"index.html", "serve.yaml", "sub/serve.yaml"} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("test"), 0644))
+	}
+
+	fs := HideFiles(http.Dir(dir), []string{"serve.yaml"})
+
+	_, err := fs.Open("/serve.yaml")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = fs.Open("/sub/../serve.yaml")
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	f, err := fs.Open("/sub/serve.yaml")
+	require.NoError(t, err)
+	f.Close()
+
+	root, err := fs.Open("/")
+	require.NoError(t, err)
+	defer root.Close()
+
+	infos, err := root.Readdir(-1)
+	require.NoError(t, err)
+
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	require.ElementsMatch(t, []string{"index.html", "sub"}, names)
+}
@@ -0,0 +1,38 @@
+package serve
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// withRequiredHost rejects requests whose Host header doesn't match one of
+// hosts with a 421 Misdirected Request. The port, if any, is ignored when
+// comparing hosts, and IPv6 addresses match with or without brackets.
+func withRequiredHost(next http.Handler, hosts []string) http.Handler {
+	allowed := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		allowed[normalizeHost(host)] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowed[normalizeHost(r.Host)] {
+			http.Error(w, http.StatusText(http.StatusMisdirectedRequest), http.StatusMisdirectedRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// normalizeHost returns host in lower case, without its port and without the
+// brackets around IPv6 addresses, e.g. ::1 for [::1]:4242.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	return strings.ToLower(host)
+}
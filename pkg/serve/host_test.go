@@ -0,0 +1,46 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRequiredHost(t *testing.T) {
+	handler := withRequiredHost(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), []string{"example.test", "Other.test"})
+
+	for host, status := range map[string]int{
+		"example.test":      http.StatusOK,
+		"example.test:4242": http.StatusOK,
+		"other.test":        http.StatusOK,
+		"localhost:4242":    http.StatusMisdirectedRequest,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, status, rr.Code, host)
+	}
+}
+
+func TestWithRequiredHostIPv6(t *testing.T) {
+	handler := withRequiredHost(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), []string{"::1", "[FE80::1]"})
+
+	for host, status := range map[string]int{
+		"[::1]":         http.StatusOK,
+		"[::1]:4242":    http.StatusOK,
+		"[fe80::1]":     http.StatusOK,
+		"[fe80::1]:443": http.StatusOK,
+		"[::2]:4242":    http.StatusMisdirectedRequest,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, status, rr.Code, host)
+	}
+}
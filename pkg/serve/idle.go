@@ -0,0 +1,43 @@
+package serve
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idleTimer calls onIdle once no request has been served for timeout.
+// Requests still in flight keep the timer from firing.
+type idleTimer struct {
+	mu       sync.Mutex
+	timer    *time.Timer
+	timeout  time.Duration
+	inFlight int
+}
+
+func newIdleTimer(timeout time.Duration, onIdle func()) *idleTimer {
+	return &idleTimer{
+		timer:   time.AfterFunc(timeout, onIdle),
+		timeout: timeout,
+	}
+}
+
+func (t *idleTimer) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.mu.Lock()
+		t.inFlight++
+		t.timer.Stop()
+		t.mu.Unlock()
+
+		defer func() {
+			t.mu.Lock()
+			t.inFlight--
+			if t.inFlight == 0 {
+				t.timer.Reset(t.timeout)
+			}
+			t.mu.Unlock()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
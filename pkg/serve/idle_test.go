@@ -0,0 +1,30 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdleTimer(t *testing.T) {
+	var idle int32
+	timer := newIdleTimer(50*time.Millisecond, func() {
+		atomic.StoreInt32(&idle, 1)
+	})
+
+	handler := timer.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+
+	time.Sleep(30 * time.Millisecond)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, int32(0), atomic.LoadInt32(&idle))
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&idle) == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,97 @@
+package serve
+
+import (
+	"fmt"
+	"html/template"
+	"mime"
+	"net/url"
+	"path"
+)
+
+// DefaultListingTemplate is the directory listing template used for
+// --generate-index. It's a page listing the entries of the directory with
+// links, sizes and types, with some basic styling.
+var DefaultListingTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Index of {{.Path}}</title>
+<style>
+  body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 2rem auto; max-width: 960px; padding: 0 1rem; color: #1a1f36; }
+  h1 { font-size: 1.25rem; font-weight: 600; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { padding: 0.5rem; text-align: left; border-bottom: 1px solid #e3e8ee; }
+  th { font-size: 0.75rem; text-transform: uppercase; color: #697386; }
+  td.size { text-align: right; white-space: nowrap; }
+  a { color: #635bff; text-decoration: none; }
+  a:hover { text-decoration: underline; }
+</style>
+</head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<table>
+  <tr><th>Name</th><th>Type</th><th>Size</th><th>Modified</th></tr>
+  {{- if ne .Path "/"}}
+  <tr><td><a href="../">../</a></td><td></td><td></td><td></td></tr>
+  {{- end}}
+  {{- range .Entries}}
+  <tr>
+    <td><a href="{{.Href}}">{{.Name}}{{if .IsDir}}/{{end}}</a></td>
+    <td>{{.Type}}</td>
+    <td class="size">{{if not .IsDir}}{{.HumanSize}}{{end}}</td>
+    <td>{{.ModTime.Format "2006-01-02 15:04"}}</td>
+  </tr>
+  {{- end}}
+</table>
+</body>
+</html>
+`))
+
+// Href returns the relative link to the entry
+func (e ListingEntry) Href() string {
+	href := "./" + url.PathEscape(e.Name)
+	if e.IsDir {
+		href += "/"
+	}
+
+	return href
+}
+
+// Type returns the MIME type of the entry guessed from its extension, or
+// "directory" for directories
+func (e ListingEntry) Type() string {
+	if e.IsDir {
+		return "directory"
+	}
+
+	mimeType := mime.TypeByExtension(path.Ext(e.Name))
+	if mimeType == "" {
+		return "unknown"
+	}
+
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return mimeType
+	}
+
+	return mediaType
+}
+
+// HumanSize returns the size of the entry formatted with a binary unit, e.g.
+// 1.5 KiB
+func (e ListingEntry) HumanSize() string {
+	const unit = 1024
+
+	if e.Size < unit {
+		return fmt.Sprintf("%d B", e.Size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := e.Size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(e.Size)/float64(div), "KMGTPE"[exp])
+}
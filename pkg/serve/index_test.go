@@ -0,0 +1,37 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultListingTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "assets"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report #1.pdf"), make([]byte, 1536), 0600))
+
+	handler := NewHandler(http.Dir(dir), &Config{ListingTemplate: DefaultListingTemplate})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	require.Contains(t, body, "<title>Index of /</title>")
+	require.Contains(t, body, `<a href="./assets/">assets/</a>`)
+	require.Contains(t, body, `<a href="./report%20%231.pdf">report #1.pdf</a>`)
+	require.Contains(t, body, "application/pdf")
+	require.Contains(t, body, "1.5 KiB")
+	require.NotContains(t, body, `href="../"`)
+}
+
+func TestListingEntryHumanSize(t *testing.T) {
+	require.Equal(t, "512 B", ListingEntry{Size: 512}.HumanSize())
+	require.Equal(t, "1.0 KiB", ListingEntry{Size: 1024}.HumanSize())
+	require.Equal(t, "3.0 MiB", ListingEntry{Size: 3 << 20}.HumanSize())
+}
@@ -0,0 +1,55 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// defaultIndexFile is the file http.FileServer serves for directories
+const defaultIndexFile = "index.html"
+
+// ValidateIndexFiles checks that names can be used as index files, i.e. that
+// they are file names without any directory.
+func ValidateIndexFiles(names []string) error {
+	for _, name := range names {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("invalid index file %q, expected a file name such as index.html", name)
+		}
+	}
+
+	return nil
+}
+
+// indexFS makes http.FileServer serve the first of names that exists in a
+// directory as its index, instead of only index.html.
+type indexFS struct {
+	fs    http.FileSystem
+	names []string
+}
+
+// Open opens name, or the first existing index file of its directory if it's
+// the index.html file that http.FileServer looks for.
+func (s *indexFS) Open(name string) (http.File, error) {
+	name = path.Clean("/" + name)
+	if path.Base(name) != defaultIndexFile {
+		return s.fs.Open(name)
+	}
+
+	dir := path.Dir(name)
+	for _, index := range s.names {
+		f, err := s.fs.Open(path.Join(dir, index))
+		if err != nil {
+			continue
+		}
+
+		if info, err := f.Stat(); err == nil && !info.IsDir() {
+			return f, nil
+		}
+		f.Close()
+	}
+
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
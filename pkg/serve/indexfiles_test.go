@@ -0,0 +1,61 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateIndexFiles(t *testing.T) {
+	require.NoError(t, ValidateIndexFiles([]string{"index.html", "200.html", "index.htm"}))
+
+	for _, name := range []string{"", "..", "docs/index.html", `docs\index.html`} {
+		require.Error(t, ValidateIndexFiles([]string{"index.html", name}), name)
+	}
+}
+
+func TestNewHandlerIndexFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"index.html":       "modern",
+		"200.html":         "surge",
+		"legacy/index.htm": "legacy",
+		"legacy/200.html":  "legacy surge",
+		"empty/.gitkeep":   "",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0600))
+	}
+
+	handler := NewHandler(http.Dir(dir), &Config{IndexFiles: []string{"index.html", "index.htm", "200.html"}})
+
+	for target, want := range map[string]string{
+		"/":        "modern",
+		"/legacy/": "legacy",
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, rr.Code, target)
+		require.Equal(t, want, rr.Body.String(), target)
+	}
+
+	// Directories without any of the index files are listed
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/empty/", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Contains(t, rr.Body.String(), ".gitkeep")
+
+	handler = NewHandler(http.Dir(dir), &Config{IndexFiles: []string{"200.html", "index.html"}})
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/legacy/", nil))
+	require.Equal(t, "legacy surge", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, "surge", rr.Body.String())
+}
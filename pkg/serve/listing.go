@@ -0,0 +1,98 @@
+package serve
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ListingEntry describes a file shown in a directory listing
+type ListingEntry struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// Listing is the data passed to the directory listing template
+type Listing struct {
+	Path    string
+	Entries []ListingEntry
+}
+
+// withListing renders the listing of directories that don't have an index
+// file with tmpl, instead of the plain listing of http.FileServer.
+func withListing(next http.Handler, fs http.FileSystem, tmpl *template.Template) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Let the file server redirect directories to their canonical path
+		// ending with a slash first
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		entries, ok := readListing(fs, r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, Listing{Path: r.URL.Path, Entries: entries})
+		if err != nil {
+			http.Error(w, "failed to render directory listing", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
+
+// readListing returns the entries of the directory name, sorted by name. It
+// returns false if name isn't a directory that should be listed.
+func readListing(fs http.FileSystem, name string) ([]ListingEntry, bool) {
+	name = path.Clean("/" + name)
+
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.IsDir() {
+		return nil, false
+	}
+
+	// Directories with an index file are served as usual
+	if index, err := fs.Open(path.Join(name, "index.html")); err == nil {
+		index.Close()
+		return nil, false
+	}
+
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		return nil, false
+	}
+
+	entries := make([]ListingEntry, 0, len(infos))
+	for _, info := range infos {
+		entries = append(entries, ListingEntry{
+			Name:    info.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   info.IsDir(),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries, true
+}
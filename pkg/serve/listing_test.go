@@ -0,0 +1,33 @@
+package serve
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithListing(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("hello"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "a"), 0700))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "site"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "site", "index.html"), []byte("index"), 0600))
+
+	tmpl := template.Must(template.New("listing").Parse(`{{.Path}}:{{range .Entries}} {{.Name}}({{.Size}},{{.IsDir}}){{end}}`))
+	fs := http.Dir(dir)
+	handler := withListing(http.FileServer(fs), fs, tmpl)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Regexp(t, `^/: a\(\d+,true\) b.txt\(5,false\) site\(\d+,true\)$`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/site/", nil))
+	require.Equal(t, "index", rr.Body.String())
+}
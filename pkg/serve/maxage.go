@@ -0,0 +1,41 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// defaultMaxAgeExt is the key of Config.MaxAgeByExt applying to extensions
+// that aren't listed
+const defaultMaxAgeExt = "*"
+
+// withMaxAge sets the Cache-Control max-age of responses from the extension
+// of the requested file, without its dot. Paths ending in a slash are served
+// an index.html file so they use the max-age of html. Extensions that aren't
+// in maxAges use the max-age of defaultMaxAgeExt, if there is one.
+func withMaxAge(next http.Handler, maxAges map[string]int) http.Handler {
+	byExt := make(map[string]int, len(maxAges))
+	for ext, maxAge := range maxAges {
+		byExt[strings.ToLower(strings.TrimPrefix(ext, "."))] = maxAge
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ext := strings.TrimPrefix(strings.ToLower(path.Ext(r.URL.Path)), ".")
+		if strings.HasSuffix(r.URL.Path, "/") {
+			ext = "html"
+		}
+
+		maxAge, ok := byExt[ext]
+		if !ok {
+			maxAge, ok = byExt[defaultMaxAgeExt]
+		}
+
+		if ok {
+			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,26 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMaxAge(t *testing.T) {
+	cacheControl := func(maxAges map[string]int, path string) string {
+		rec := httptest.NewRecorder()
+		withMaxAge(http.NotFoundHandler(), maxAges).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Header().Get("Cache-Control")
+	}
+
+	maxAges := map[string]int{"js": 31536000, ".HTML": 0}
+	require.Equal(t, "max-age=31536000", cacheControl(maxAges, "/app.123abc.JS"))
+	require.Equal(t, "max-age=0", cacheControl(maxAges, "/index.html"))
+	require.Equal(t, "max-age=0", cacheControl(maxAges, "/docs/"))
+	require.Empty(t, cacheControl(maxAges, "/logo.png"))
+
+	maxAges["*"] = 60
+	require.Equal(t, "max-age=60", cacheControl(maxAges, "/logo.png"))
+}
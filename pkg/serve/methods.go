@@ -0,0 +1,29 @@
+package serve
+
+import (
+	"net/http"
+	"strings"
+)
+
+// withAllowedMethods rejects requests whose method isn't listed in methods
+// with a 405 Method Not Allowed.
+func withAllowedMethods(next http.Handler, methods []string) http.Handler {
+	allowed := make(map[string]bool, len(methods))
+	names := make([]string, 0, len(methods))
+	for _, method := range methods {
+		method = strings.ToUpper(method)
+		allowed[method] = true
+		names = append(names, method)
+	}
+	allowHeader := strings.Join(names, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowed[r.Method] {
+			w.Header().Set("Allow", allowHeader)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,78 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// notifyTimeout bounds how long a request notification can take, so that
+// slow receivers don't pile up goroutines
+const notifyTimeout = 3 * time.Second
+
+// requestNotification is the JSON payload posted for every request
+type requestNotification struct {
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote_addr"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// ParseNotifyURL parses the URL that request notifications are posted to
+func ParseNotifyURL(value string) (*url.URL, error) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid notification URL %q, expected an absolute http or https URL", value)
+	}
+
+	return u, nil
+}
+
+// withRequestNotifications posts a requestNotification to target for every
+// request. The notifications are sent in the background and their failures
+// are only logged, so they never delay or fail the response.
+func withRequestNotifications(next http.Handler, target *url.URL) http.Handler {
+	client := &http.Client{Timeout: notifyTimeout}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notification := requestNotification{
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			RemoteAddr: r.RemoteAddr,
+			Timestamp:  time.Now().UTC(),
+		}
+
+		go notify(client, target.String(), notification)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func notify(client *http.Client, target string, notification requestNotification) {
+	logger := log.WithFields(log.Fields{
+		"prefix": "serve.notify",
+		"url":    target,
+	})
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		logger.Debugf("Failed to encode request notification: %v", err)
+		return
+	}
+
+	resp, err := client.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.Debugf("Failed to send request notification: %v", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		logger.Debugf("Request notification was rejected with status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,70 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNotifyURL(t *testing.T) {
+	u, err := ParseNotifyURL("http://localhost:8080/hook")
+	require.NoError(t, err)
+	require.Equal(t, "localhost:8080", u.Host)
+
+	for _, value := range []string{"", "localhost:8080", "/hook", "ftp://localhost/hook"} {
+		_, err := ParseNotifyURL(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestWithRequestNotifications(t *testing.T) {
+	notifications := make(chan requestNotification, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification requestNotification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		notifications <- notification
+	}))
+	defer receiver.Close()
+
+	target, err := ParseNotifyURL(receiver.URL)
+	require.NoError(t, err)
+
+	handler := withRequestNotifications(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}), target)
+
+	req := httptest.NewRequest(http.MethodHead, "/index.html", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, "ok", rec.Body.String())
+
+	select {
+	case notification := <-notifications:
+		require.Equal(t, http.MethodHead, notification.Method)
+		require.Equal(t, "/index.html", notification.Path)
+		require.Equal(t, "192.0.2.1:1234", notification.RemoteAddr)
+		require.WithinDuration(t, time.Now(), notification.Timestamp, time.Minute)
+	case <-time.After(time.Second):
+		t.Fatal("no notification received")
+	}
+}
+
+func TestWithRequestNotificationsUnreachable(t *testing.T) {
+	target, err := ParseNotifyURL("http://127.0.0.1:1/hook")
+	require.NoError(t, err)
+
+	handler := withRequestNotifications(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}), target)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "ok", rec.Body.String())
+}
@@ -0,0 +1,96 @@
+package serve
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// defaultUnprivilegedPortStart is the first port that any user can listen on
+// when the kernel doesn't say otherwise
+const defaultUnprivilegedPortStart = 1024
+
+// The files the kernel reports the privileged ports and the capabilities of
+// the process in
+const (
+	unprivilegedPortStartFile = "/proc/sys/net/ipv4/ip_unprivileged_port_start"
+	procStatusFile            = "/proc/self/status"
+)
+
+// capNetBindService is the number of the CAP_NET_BIND_SERVICE capability,
+// which allows listening on privileged ports
+const capNetBindService = 10
+
+// CheckPortPermission returns an error with a hint when port is privileged
+// and the process isn't allowed to listen on it, rather than letting the
+// listener fail with a bare "permission denied". Only Linux is checked: macOS
+// lets any user listen on all interfaces with any port, and Windows has no
+// privileged ports.
+func CheckPortPermission(port int) error {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+
+	return checkPortPermission(port, os.Geteuid(), unprivilegedPortStartFile, procStatusFile)
+}
+
+// checkPortPermission is CheckPortPermission for a process running as euid,
+// reading the kernel settings from the given files.
+func checkPortPermission(port, euid int, startFile, statusFile string) error {
+	if euid == 0 {
+		return nil
+	}
+
+	start := unprivilegedPortStart(startFile)
+	if port >= start || hasCapability(statusFile, capNetBindService) {
+		return nil
+	}
+
+	return fmt.Errorf("port %d is privileged and can only be used by root, use a port of %d or above such as 4242 instead, or run the command as root", port, start)
+}
+
+// unprivilegedPortStart returns the first port that any user can listen on,
+// which can be changed with the net.ipv4.ip_unprivileged_port_start sysctl
+func unprivilegedPortStart(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return defaultUnprivilegedPortStart
+	}
+
+	start, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return defaultUnprivilegedPortStart
+	}
+
+	return start
+}
+
+// hasCapability returns whether the effective capabilities of the process,
+// read from its status file, include the numbered capability
+func hasCapability(path string, capability uint) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value := strings.TrimPrefix(scanner.Text(), "CapEff:")
+		if value == scanner.Text() {
+			continue
+		}
+
+		caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return false
+		}
+
+		return caps&(1<<capability) != 0
+	}
+
+	return false
+}
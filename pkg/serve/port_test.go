@@ -0,0 +1,56 @@
+package serve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeProcFiles writes fake kernel files with the given first unprivileged
+// port and effective capabilities
+func writeProcFiles(t *testing.T, start, capEff string) (string, string) {
+	dir := t.TempDir()
+	startFile := filepath.Join(dir, "ip_unprivileged_port_start")
+	statusFile := filepath.Join(dir, "status")
+
+	require.NoError(t, os.WriteFile(startFile, []byte(start+"\n"), 0600))
+	require.NoError(t, os.WriteFile(statusFile, []byte("Name:\tstripe\nCapInh:\t0000000000000000\nCapEff:\t"+capEff+"\n"), 0600))
+
+	return startFile, statusFile
+}
+
+func TestCheckPortPermission(t *testing.T) {
+	require.NoError(t, CheckPortPermission(4242))
+	require.NoError(t, CheckPortPermission(65535))
+}
+
+func TestCheckPortPermissionPrivileged(t *testing.T) {
+	startFile, statusFile := writeProcFiles(t, "1024", "0000000000000000")
+
+	err := checkPortPermission(80, 1000, startFile, statusFile)
+	require.EqualError(t, err, "port 80 is privileged and can only be used by root, use a port of 1024 or above such as 4242 instead, or run the command as root")
+
+	require.NoError(t, checkPortPermission(8080, 1000, startFile, statusFile))
+	require.NoError(t, checkPortPermission(80, 0, startFile, statusFile))
+}
+
+func TestCheckPortPermissionNetBindCapability(t *testing.T) {
+	// CAP_NET_BIND_SERVICE is bit 10
+	startFile, statusFile := writeProcFiles(t, "1024", "0000000000000400")
+
+	require.NoError(t, checkPortPermission(80, 1000, startFile, statusFile))
+}
+
+func TestCheckPortPermissionUnprivilegedPortStart(t *testing.T) {
+	startFile, statusFile := writeProcFiles(t, "80", "0000000000000000")
+
+	require.NoError(t, checkPortPermission(80, 1000, startFile, statusFile))
+	require.Error(t, checkPortPermission(79, 1000, startFile, statusFile))
+
+	// Without the sysctl, ports below 1024 are privileged
+	missing := filepath.Join(t.TempDir(), "missing")
+	require.Error(t, checkPortPermission(1023, 1000, missing, statusFile))
+	require.NoError(t, checkPortPermission(1024, 1000, missing, statusFile))
+}
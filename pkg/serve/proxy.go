@@ -0,0 +1,77 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Proxy forwards the requests whose path starts with Prefix to Target
+type Proxy struct {
+	Prefix string
+	Target *url.URL
+}
+
+// ParseProxy parses a proxy formatted as <prefix>=<url>, e.g.
+// /api=http://localhost:3000.
+func ParseProxy(value string) (Proxy, error) {
+	prefix, target, ok := strings.Cut(value, "=")
+	if !ok || !strings.HasPrefix(prefix, "/") {
+		return Proxy{}, fmt.Errorf("invalid proxy %q, expected a value such as /api=http://localhost:3000", value)
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Proxy{}, fmt.Errorf("invalid proxy %q, the target must be an absolute http or https URL", value)
+	}
+
+	return Proxy{Prefix: prefix, Target: u}, nil
+}
+
+// withProxies forwards the requests matching one of proxies to its target,
+// and the others to next. When several prefixes match, the longest one wins.
+//
+// Websocket connections, such as the hot reloading ones of frontend dev
+// servers, are proxied too: httputil.ReverseProxy detects the Upgrade header
+// and pipes the hijacked connection to the target once it has switched
+// protocols. This requires the ResponseWriter to be an http.Hijacker, so the
+// handlers wrapping this one must not hide it.
+func withProxies(next http.Handler, proxies []Proxy) http.Handler {
+	proxies = append([]Proxy(nil), proxies...)
+	sort.SliceStable(proxies, func(i, j int) bool {
+		return len(proxies[i].Prefix) > len(proxies[j].Prefix)
+	})
+
+	handlers := make([]http.Handler, len(proxies))
+	for i, proxy := range proxies {
+		handlers[i] = newReverseProxy(proxy.Target)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i, proxy := range proxies {
+			if strings.HasPrefix(r.URL.Path, proxy.Prefix) {
+				handlers[i].ServeHTTP(w, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newReverseProxy(target *url.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+
+		// Dev servers often only accept requests for their own host
+		r.Host = target.Host
+	}
+
+	return proxy
+}
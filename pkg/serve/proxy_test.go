@@ -0,0 +1,98 @@
+package serve
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProxy(t *testing.T) {
+	proxy, err := ParseProxy("/api=http://localhost:3000")
+	require.NoError(t, err)
+	require.Equal(t, "/api", proxy.Prefix)
+	require.Equal(t, "localhost:3000", proxy.Target.Host)
+
+	for _, value := range []string{"http://localhost:3000", "api=http://localhost:3000", "/api=localhost:3000", "/api=ftp://localhost"} {
+		_, err = ParseProxy(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestWithProxies(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Host, r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	target, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	handler := withProxies(http.NotFoundHandler(), []Proxy{{Prefix: "/api", Target: target}})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/users", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, target.Host+" /api/users", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestWithProxiesWebsocket(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			messageType, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, message); err != nil {
+				return
+			}
+		}
+	}))
+	defer upstream.Close()
+
+	target, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	fs := http.Dir(t.TempDir())
+	server := httptest.NewServer(NewHandler(fs, &Config{
+		Methods: []string{http.MethodGet},
+		Proxies: []Proxy{{Prefix: "/hmr", Target: target}},
+	}))
+	defer server.Close()
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/hmr", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("reload")))
+
+	messageType, message, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, websocket.TextMessage, messageType)
+	require.Equal(t, "reload", string(message))
+
+	// Methods are only restricted for the served files
+	resp, err = http.Post(server.URL+"/hmr", "text/plain", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	require.NotEqual(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
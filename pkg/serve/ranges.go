@@ -0,0 +1,42 @@
+package serve
+
+import (
+	"net/http"
+)
+
+// withoutRanges makes next ignore the Range header of requests and always
+// serve whole files, advertising it with Accept-Ranges: none.
+func withoutRanges(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del("Range")
+		r.Header.Del("If-Range")
+
+		next.ServeHTTP(&noRangesWriter{ResponseWriter: w}, r)
+	})
+}
+
+// noRangesWriter sets Accept-Ranges: none on responses, overriding the value
+// set by http.ServeContent.
+type noRangesWriter struct {
+	http.ResponseWriter
+
+	wroteHeader bool
+}
+
+func (w *noRangesWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	w.Header().Set("Accept-Ranges", "none")
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *noRangesWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
@@ -0,0 +1,137 @@
+package serve
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucketIdleTimeout is how long a client's bucket is kept after its last
+// request. By then the bucket is full again, so forgetting it changes nothing.
+const bucketIdleTimeout = time.Minute
+
+// Rate is a number of requests allowed per period
+type Rate struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseRate parses a rate formatted as <requests>/<unit>, where unit is one of
+// s, m or h, e.g. 10/s.
+func ParseRate(value string) (Rate, error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return Rate{}, fmt.Errorf("invalid rate %q, expected a value such as 10/s", value)
+	}
+
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q, the number of requests must be a positive integer", value)
+	}
+
+	per, ok := map[string]time.Duration{
+		"s": time.Second,
+		"m": time.Minute,
+		"h": time.Hour,
+	}[parts[1]]
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q, the unit must be one of s, m, h", value)
+	}
+
+	return Rate{Requests: requests, Per: per}, nil
+}
+
+// tokenBucket holds the tokens available to a client. A token is used by each
+// request and tokens are refilled continuously, up to the burst size.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits the rate of requests per client IP address
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	burst     float64
+	perToken  time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newRateLimiter(rate Rate) *rateLimiter {
+	return &rateLimiter{
+		buckets:  make(map[string]*tokenBucket),
+		burst:    float64(rate.Requests),
+		perToken: rate.Per / time.Duration(rate.Requests),
+		now:      time.Now,
+	}
+}
+
+// allow reports whether client can make a request now, and if not, how long
+// it has to wait before it can.
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+float64(now.Sub(bucket.last))/float64(l.perToken))
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) * float64(l.perToken))
+	}
+
+	bucket.tokens--
+
+	return true, 0
+}
+
+// sweep forgets the buckets of clients that have been idle for a while, to
+// bound the memory used by the limiter.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketIdleTimeout {
+		return
+	}
+	l.lastSweep = now
+
+	for client, bucket := range l.buckets {
+		if now.Sub(bucket.last) > bucketIdleTimeout {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// withRateLimit responds with a 429 Too Many Requests to clients exceeding
+// rate.
+func withRateLimit(next http.Handler, rate Rate) http.Handler {
+	limiter := newRateLimiter(rate)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(client); err == nil {
+			client = host
+		}
+
+		ok, wait := limiter.allow(client)
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
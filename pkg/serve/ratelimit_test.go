@@ -0,0 +1,47 @@
+package serve
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	rate, err := ParseRate("10/s")
+	require.NoError(t, err)
+	require.Equal(t, Rate{Requests: 10, Per: time.Second}, rate)
+
+	for _, value := range []string{"10", "0/s", "ten/s", "10/d"} {
+		_, err = ParseRate(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(Rate{Requests: 2, Per: time.Second})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		ok, _ := limiter.allow("127.0.0.1")
+		require.True(t, ok)
+	}
+
+	ok, wait := limiter.allow("127.0.0.1")
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	// Other clients have their own bucket
+	ok, _ = limiter.allow("10.0.0.1")
+	require.True(t, ok)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = limiter.allow("127.0.0.1")
+	require.True(t, ok)
+
+	now = now.Add(2 * bucketIdleTimeout)
+	ok, _ = limiter.allow("127.0.0.1")
+	require.True(t, ok)
+	require.Len(t, limiter.buckets, 1)
+}
@@ -0,0 +1,38 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ValidateRedirectCode checks that code is a redirect status code
+func ValidateRedirectCode(code int) error {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return nil
+	default:
+		return fmt.Errorf("invalid redirect code %d, expected one of 301, 302, 303, 307, 308", code)
+	}
+}
+
+// withRedirectCode replaces the status of the redirects issued by
+// http.FileServer, which always uses 301 Moved Permanently, with code.
+func withRedirectCode(next http.Handler, code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&redirectCodeWriter{ResponseWriter: w, code: code}, r)
+	})
+}
+
+type redirectCodeWriter struct {
+	http.ResponseWriter
+
+	code int
+}
+
+func (w *redirectCodeWriter) WriteHeader(code int) {
+	if code == http.StatusMovedPermanently {
+		code = w.code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
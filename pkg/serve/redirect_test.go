@@ -0,0 +1,32 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRedirectCode(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "docs"), 0700))
+
+	handler := NewHandler(http.Dir(dir), &Config{RedirectCode: http.StatusFound})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, "docs/", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	NewHandler(http.Dir(dir), &Config{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusMovedPermanently, rec.Code)
+}
+
+func TestValidateRedirectCode(t *testing.T) {
+	require.NoError(t, ValidateRedirectCode(http.StatusTemporaryRedirect))
+	require.Error(t, ValidateRedirectCode(http.StatusOK))
+}
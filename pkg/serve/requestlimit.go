@@ -0,0 +1,90 @@
+package serve
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// requestLimit calls onLimit once max requests have been served with a 2xx
+// status.
+type requestLimit struct {
+	mu      sync.Mutex
+	max     int
+	served  int
+	onLimit func()
+}
+
+func newRequestLimit(max int, onLimit func()) *requestLimit {
+	return &requestLimit{
+		max:     max,
+		onLimit: onLimit,
+	}
+}
+
+func (l *requestLimit) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		if sw.status < 200 || sw.status > 299 {
+			return
+		}
+
+		l.mu.Lock()
+		l.served++
+		reached := l.served == l.max
+		l.mu.Unlock()
+
+		if reached && l.onLimit != nil {
+			l.onLimit()
+		}
+	})
+}
+
+// statusWriter records the status and body size of the response written
+// through it
+type statusWriter struct {
+	http.ResponseWriter
+
+	status int
+	size   int64
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Flush lets throttled and streamed responses through
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets proxied websocket connections through
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T doesn't support hijacking", w.ResponseWriter)
+	}
+
+	return hijacker.Hijack()
+}
@@ -0,0 +1,40 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLimit(t *testing.T) {
+	var reached int
+	limit := newRequestLimit(2, func() {
+		reached++
+	})
+
+	handler := limit.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	serve := func(path string) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	serve("/")
+	serve("/missing")
+	serve("/missing")
+	require.Equal(t, 0, reached)
+
+	serve("/")
+	require.Equal(t, 1, reached)
+
+	// The limit is only reported once
+	serve("/")
+	require.Equal(t, 1, reached)
+}
@@ -0,0 +1,39 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// CheckRequiredFiles returns an error listing the paths that aren't files in
+// fs, so that a broken build fails when the server starts rather than with
+// 404s once it's in use.
+func CheckRequiredFiles(fs http.FileSystem, paths []string) error {
+	var missing []string
+
+	for _, p := range paths {
+		if !isFile(fs, path.Clean("/"+p)) {
+			missing = append(missing, p)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("required files are missing: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func isFile(fs http.FileSystem, name string) bool {
+	f, err := fs.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,25 @@
+package serve
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRequiredFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "assets"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte(""), 0600))
+
+	fs := http.Dir(dir)
+
+	require.NoError(t, CheckRequiredFiles(fs, []string{"index.html", "/assets/app.js"}))
+	require.EqualError(t,
+		CheckRequiredFiles(fs, []string{"index.html", "assets", "app.css", "../index.html"}),
+		"required files are missing: assets, app.css",
+	)
+}
@@ -0,0 +1,123 @@
+package serve
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// withHTMLRewrite passes the body of successful text/html responses through
+// rewrite before sending them to the client. Other responses are untouched.
+func withHTMLRewrite(next http.Handler, fs http.FileSystem, rewrite func([]byte) []byte) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Byte ranges of the file on disk don't line up with the rewritten
+		// body, so ask for the whole file when it may be HTML. Ranges of
+		// other files such as videos keep working.
+		if r.Header.Get("Range") != "" && mayServeHTML(fs, r.URL.Path) {
+			r.Header.Del("Range")
+		}
+
+		rw := &htmlRewriteWriter{ResponseWriter: w, rewrite: rewrite}
+
+		// The body of HEAD responses is empty, so get the page to compute the
+		// length of the rewritten one, without sending it
+		if r.Method == http.MethodHead && mayServeHTML(fs, r.URL.Path) {
+			r = r.Clone(r.Context())
+			r.Method = http.MethodGet
+			rw.head = true
+		}
+
+		next.ServeHTTP(rw, r)
+		rw.flush()
+	})
+}
+
+// mayServeHTML returns whether a request for name may be answered with an
+// HTML page: the file has an HTML extension or no extension at all, whose
+// type is sniffed from the contents, or it's a directory, whose index is served, or it doesn't exist, in which
+// case a fallback page may be served.
+func mayServeHTML(fs http.FileSystem, name string) bool {
+	f, err := fs.Open(name)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return true
+	}
+
+	ext := path.Ext(name)
+
+	return ext == "" || strings.HasPrefix(mime.TypeByExtension(ext), "text/html")
+}
+
+// htmlRewriteWriter buffers text/html responses so that they can be rewritten
+// once the wrapped handler is done writing them.
+type htmlRewriteWriter struct {
+	http.ResponseWriter
+
+	rewrite     func([]byte) []byte
+	head        bool
+	status      int
+	buf         *bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *htmlRewriteWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if code == http.StatusOK && strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		w.status = code
+		w.buf = new(bytes.Buffer)
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *htmlRewriteWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.buf != nil {
+		return w.buf.Write(p)
+	}
+
+	// Responses to HEAD requests that turn out not to be rewritten have no
+	// body either
+	if w.head {
+		return len(p), nil
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *htmlRewriteWriter) flush() {
+	if w.buf == nil {
+		return
+	}
+
+	body := w.rewrite(w.buf.Bytes())
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(w.status)
+	if !w.head {
+		w.ResponseWriter.Write(body)
+	}
+}
+
+// replacePlaceholder returns a rewrite func replacing every occurrence of
+// placeholder with value.
+func replacePlaceholder(placeholder, value string) func([]byte) []byte {
+	return func(body []byte) []byte {
+		return bytes.ReplaceAll(body, []byte(placeholder), []byte(value))
+	}
+}
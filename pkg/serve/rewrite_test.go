@@ -0,0 +1,74 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHTMLRewrite(t *testing.T) {
+	handler := withHTMLRewrite(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		case "/app.js":
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		}
+		w.Write([]byte(`Stripe("{{STRIPE_PK}}")`))
+	}), http.Dir(t.TempDir()), replacePlaceholder(PublishableKeyPlaceholder, "pk_test_123"))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	require.Equal(t, `Stripe("pk_test_123")`, rr.Body.String())
+	require.Equal(t, "21", rr.Header().Get("Content-Length"))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	require.Equal(t, `Stripe("{{STRIPE_PK}}")`, rr.Body.String())
+}
+
+func TestWithHTMLRewriteRanges(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte(`Stripe("{{STRIPE_PK}}")`), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "video.mp4"), []byte("0123456789"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("0123456789"), 0600))
+
+	handler := NewHandler(http.Dir(dir), &Config{PublishableKey: "pk_test_123"})
+
+	// Ranges of other files are still served
+	for _, name := range []string{"/video.mp4", "/app.js"} {
+		req := httptest.NewRequest(http.MethodGet, name, nil)
+		req.Header.Set("Range", "bytes=2-4")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusPartialContent, rr.Code, name)
+		require.Equal(t, "234", rr.Body.String(), name)
+	}
+
+	// HTML files are always rewritten as a whole
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=2-4")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, `Stripe("pk_test_123")`, rr.Body.String())
+}
+
+func TestWithHTMLRewriteHead(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte(`Stripe("{{STRIPE_PK}}")`), 0600))
+
+	handler := NewHandler(http.Dir(dir), &Config{PublishableKey: "pk_test_123"})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodHead, "/", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "21", rr.Header().Get("Content-Length"))
+	require.Empty(t, rr.Body.String())
+}
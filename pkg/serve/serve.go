@@ -0,0 +1,226 @@
+// Package serve implements the static file server used by `stripe serve`.
+package serve
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// PublishableKeyPlaceholder is the token replaced by Config.PublishableKey in
+// served HTML files
+const PublishableKeyPlaceholder = "{{STRIPE_PK}}"
+
+// Config provides the configuration of the static file server
+type Config struct {
+	// Delay is an artificial latency applied before every response
+	Delay time.Duration
+	// DelayJitter is the upper bound of a random extra latency added to Delay
+	DelayJitter time.Duration
+	// PublishableKey, if set, replaces PublishableKeyPlaceholder in HTML responses
+	PublishableKey string
+	// BaseHref, if set, is injected as a <base> tag in the <head> of HTML
+	// responses
+	BaseHref string
+	// NoSniff sets the X-Content-Type-Options: nosniff header on all responses
+	NoSniff bool
+	// RequiredHosts, if not empty, lists the only Host headers that are served
+	RequiredHosts []string
+	// ListingTemplate, if set, is used to render directory listings
+	ListingTemplate *template.Template
+	// Methods, if not empty, lists the only HTTP methods that are served
+	Methods []string
+	// IdleTimeout, if set, is how long the server can go without serving a
+	// request before OnIdle is called
+	IdleTimeout time.Duration
+	// OnIdle is called when the server has been idle for IdleTimeout
+	OnIdle func()
+	// MaxRequests, if set, is how many successful requests the server can
+	// serve before OnMaxRequests is called
+	MaxRequests int
+	// OnMaxRequests is called once MaxRequests requests have been served
+	// with a 2xx status
+	OnMaxRequests func()
+	// SourceMaps sets the SourceMap header on JavaScript files with a .map
+	// file next to them
+	SourceMaps bool
+	// VerboseErrors includes the underlying error and the requested path in
+	// the body of 5xx responses
+	VerboseErrors bool
+	// ErrorPage500, if set, is the HTML document served as the body of 500
+	// responses
+	ErrorPage500 []byte
+	// Download sets the Content-Disposition header so that browsers download
+	// files instead of displaying them
+	Download bool
+	// DownloadExtensions restricts Download to the files with these
+	// extensions, and enables it when not empty
+	DownloadExtensions []string
+	// PathDelays are extra delays applied to the requests under some paths
+	PathDelays []PathDelay
+	// Proxies forward the requests matching their prefix to another server
+	// instead of serving files
+	Proxies []Proxy
+	// MaxAgeByExt sets the Cache-Control max-age of files from their
+	// extension, with the * entry applying to the other extensions
+	MaxAgeByExt map[string]int
+	// RedirectCode replaces the 301 status of the redirects to the canonical
+	// path of directories and index files
+	RedirectCode int
+	// CacheFiles keeps the contents of small files in memory
+	CacheFiles bool
+	// NoRanges ignores the Range header of requests and always serves whole
+	// files
+	NoRanges bool
+	// NoConditional ignores the If-Modified-Since and If-None-Match headers
+	// of requests and never responds with 304 Not Modified
+	NoConditional bool
+	// RateLimit, if set, limits the rate of requests per client IP address
+	RateLimit *Rate
+	// Bandwidth, if set, is the maximum speed at which each response body
+	// is written, in bytes per second
+	Bandwidth int64
+	// IndexFiles, if not empty, are the files served for directories, the
+	// first one that exists winning, instead of index.html
+	IndexFiles []string
+	// SPA serves the root index.html instead of a 404 for the paths that
+	// don't exist, for single page applications with client side routing
+	SPA bool
+	// SPAStatus is the status of the responses serving the SPA fallback
+	SPAStatus int
+	// Available, if set, is called for every request, which gets a 503
+	// Service Unavailable when it returns false
+	Available func() bool
+	// NotifyURL, if set, is sent a JSON description of every request
+	NotifyURL *url.URL
+	// TarEndpoint, if set, is the path serving all the files as a gzipped
+	// tarball
+	TarEndpoint string
+	// HAR, if set, records every request served
+	HAR *HARRecorder
+}
+
+// NewHandler returns an http.Handler that serves the files found in fs and
+// applies the behaviors enabled in cfg.
+func NewHandler(fs http.FileSystem, cfg *Config) http.Handler {
+	if len(cfg.IndexFiles) > 0 && !(len(cfg.IndexFiles) == 1 && cfg.IndexFiles[0] == defaultIndexFile) {
+		fs = &indexFS{fs: fs, names: cfg.IndexFiles}
+	}
+
+	if cfg.CacheFiles {
+		fs = newCachedFS(fs, cacheMaxFileSize, cacheMaxTotalSize)
+	}
+
+	var handler http.Handler = withContentTypes(http.FileServer(fs))
+
+	if cfg.SPA {
+		handler = withSPAFallback(handler, fs, cfg.SPAStatus)
+	}
+
+	if cfg.RedirectCode != 0 && cfg.RedirectCode != http.StatusMovedPermanently {
+		handler = withRedirectCode(handler, cfg.RedirectCode)
+	}
+
+	if cfg.NoRanges {
+		handler = withoutRanges(handler)
+	}
+
+	if cfg.NoConditional {
+		handler = withoutConditionals(handler)
+	}
+
+	if cfg.VerboseErrors {
+		handler = withVerboseErrors(handler, fs)
+	}
+
+	if len(cfg.ErrorPage500) > 0 {
+		handler = withErrorPage(handler, cfg.ErrorPage500)
+	}
+
+	if cfg.SourceMaps {
+		handler = withSourceMaps(handler, fs)
+	}
+
+	if cfg.Download || len(cfg.DownloadExtensions) > 0 {
+		handler = withDownloads(handler, fs, cfg.DownloadExtensions)
+	}
+
+	if cfg.ListingTemplate != nil {
+		handler = withListing(handler, fs, cfg.ListingTemplate)
+	}
+
+	if cfg.TarEndpoint != "" {
+		handler = withTarEndpoint(handler, fs, cfg.TarEndpoint)
+	}
+
+	if cfg.PublishableKey != "" {
+		handler = withHTMLRewrite(handler, fs, replacePlaceholder(PublishableKeyPlaceholder, cfg.PublishableKey))
+	}
+
+	if cfg.BaseHref != "" {
+		handler = withHTMLRewrite(handler, fs, injectBase(cfg.BaseHref))
+	}
+
+	if len(cfg.MaxAgeByExt) > 0 {
+		handler = withMaxAge(handler, cfg.MaxAgeByExt)
+	}
+
+	headers := make(http.Header)
+	if cfg.NoSniff {
+		headers.Set("X-Content-Type-Options", "nosniff")
+	}
+	if len(headers) > 0 {
+		handler = withHeaders(handler, headers)
+	}
+
+	if len(cfg.PathDelays) > 0 {
+		handler = withPathDelays(handler, cfg.PathDelays)
+	}
+
+	if cfg.Bandwidth > 0 {
+		handler = withBandwidth(handler, cfg.Bandwidth)
+	}
+
+	if cfg.Delay > 0 || cfg.DelayJitter > 0 {
+		handler = withDelay(handler, cfg.Delay, cfg.DelayJitter)
+	}
+
+	if cfg.RateLimit != nil {
+		handler = withRateLimit(handler, *cfg.RateLimit)
+	}
+
+	if len(cfg.Methods) > 0 {
+		handler = withAllowedMethods(handler, cfg.Methods)
+	}
+
+	if cfg.Available != nil {
+		handler = withAvailability(handler, cfg.Available)
+	}
+
+	if len(cfg.Proxies) > 0 {
+		handler = withProxies(handler, cfg.Proxies)
+	}
+
+	if cfg.NotifyURL != nil {
+		handler = withRequestNotifications(handler, cfg.NotifyURL)
+	}
+
+	if len(cfg.RequiredHosts) > 0 {
+		handler = withRequiredHost(handler, cfg.RequiredHosts)
+	}
+
+	if cfg.IdleTimeout > 0 {
+		handler = newIdleTimer(cfg.IdleTimeout, cfg.OnIdle).wrap(handler)
+	}
+
+	if cfg.MaxRequests > 0 {
+		handler = newRequestLimit(cfg.MaxRequests, cfg.OnMaxRequests).wrap(handler)
+	}
+
+	if cfg.HAR != nil {
+		handler = cfg.HAR.wrap(handler)
+	}
+
+	return handler
+}
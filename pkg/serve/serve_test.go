@@ -0,0 +1,132 @@
+package serve
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHandlerSingleRange(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "video.mp4"), []byte("0123456789"), 0600))
+
+	req := httptest.NewRequest(http.MethodGet, "/video.mp4", nil)
+	req.Header.Set("Range", "bytes=2-4")
+
+	rr := httptest.NewRecorder()
+	NewHandler(http.Dir(dir), &Config{}).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusPartialContent, rr.Code)
+	require.Equal(t, "bytes 2-4/10", rr.Header().Get("Content-Range"))
+	require.Equal(t, "234", rr.Body.String())
+}
+
+func TestNewHandlerNoRanges(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "video.mp4"), []byte("0123456789"), 0600))
+
+	req := httptest.NewRequest(http.MethodGet, "/video.mp4", nil)
+	req.Header.Set("Range", "bytes=2-4")
+
+	rr := httptest.NewRecorder()
+	NewHandler(http.Dir(dir), &Config{NoRanges: true}).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "none", rr.Header().Get("Accept-Ranges"))
+	require.Empty(t, rr.Header().Get("Content-Range"))
+	require.Equal(t, "0123456789", rr.Body.String())
+}
+
+func TestNewHandlerNoConditional(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("render()"), 0600))
+
+	rr := httptest.NewRecorder()
+	NewHandler(http.Dir(dir), &Config{}).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	lastModified := rr.Header().Get("Last-Modified")
+	require.NotEmpty(t, lastModified)
+
+	for _, tc := range []struct {
+		noConditional bool
+		want          int
+	}{
+		{false, http.StatusNotModified},
+		{true, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+		req.Header.Set("If-Modified-Since", lastModified)
+
+		rr := httptest.NewRecorder()
+		NewHandler(http.Dir(dir), &Config{NoConditional: tc.noConditional}).ServeHTTP(rr, req)
+		require.Equal(t, tc.want, rr.Code)
+	}
+
+	// ETags set by other handlers are ignored too
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "app.js", time.Time{}, strings.NewReader("render()"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("If-None-Match", `"v1"`)
+
+	rr = httptest.NewRecorder()
+	withoutConditionals(handler).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "render()", rr.Body.String())
+}
+
+func TestNewHandlerMultiRange(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "video.mp4"), []byte("0123456789"), 0600))
+
+	tarFS, err := OpenTar(writeTestTar(t, true))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		fs   http.FileSystem
+		path string
+		want []string
+	}{
+		{http.Dir(dir), "/video.mp4", []string{"01", "789"}},
+		{http.FS(tarFS), "/dist/js/app.js", []string{"al", "(1)"}},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Range", "bytes=0-1,-3")
+
+		rr := httptest.NewRecorder()
+		NewHandler(tc.fs, &Config{}).ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusPartialContent, rr.Code)
+
+		mediaType, params, err := mime.ParseMediaType(rr.Header().Get("Content-Type"))
+		require.NoError(t, err)
+		require.Equal(t, "multipart/byteranges", mediaType)
+
+		var parts []string
+		mr := multipart.NewReader(rr.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			require.NotEmpty(t, part.Header.Get("Content-Range"))
+
+			body, err := io.ReadAll(part)
+			require.NoError(t, err)
+			parts = append(parts, string(body))
+		}
+
+		require.Equal(t, tc.want, parts)
+	}
+}
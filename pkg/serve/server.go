@@ -0,0 +1,121 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	stdlog "log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// clientDisconnectMessages are the fragments of errors reported by net/http
+// when a client goes away in the middle of a response, e.g. when a browser
+// cancels a download or navigates away from a page.
+var clientDisconnectMessages = []string{
+	"broken pipe",
+	"connection reset by peer",
+	"forcibly closed by the remote host",
+}
+
+// NewServer returns an http.Server listening on addr and serving handler.
+// Errors caused by clients disconnecting are only logged at the debug level.
+func NewServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:     addr,
+		Handler:  handler,
+		ErrorLog: stdlog.New(errorLogWriter{}, "", 0),
+	}
+}
+
+// shutdownTimeout is how long in-flight requests are given to complete when
+// the server is stopped
+const shutdownTimeout = 5 * time.Second
+
+// ListenAndServe runs server until it fails or ctx is canceled, in which case
+// the server is gracefully shut down. The server uses HTTPS if it has a TLS
+// config.
+func ListenAndServe(ctx context.Context, server *http.Server) error {
+	ln, err := Listen(server)
+	if err != nil {
+		return err
+	}
+
+	return Serve(ctx, server, ln)
+}
+
+// Listen binds the address of server, so that the actual address is known
+// before serving, e.g. when listening on port 0.
+func Listen(server *http.Server) (net.Listener, error) {
+	addr := server.Addr
+	if addr == "" {
+		addr = ":http"
+		if server.TLSConfig != nil {
+			addr = ":https"
+		}
+	}
+
+	return net.Listen("tcp", addr)
+}
+
+// Serve is like ListenAndServe, with a listener returned by Listen.
+func Serve(ctx context.Context, server *http.Server, ln net.Listener) error {
+	errCh := make(chan error, 1)
+
+	go func() {
+		if server.TLSConfig != nil {
+			// The certificates are provided by the TLS config
+			errCh <- server.ServeTLS(ln, "", "")
+		} else {
+			errCh <- server.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := server.Shutdown(shutdownCtx)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// isClientDisconnect checks whether a server error message was caused by the
+// client closing the connection.
+func isClientDisconnect(msg string) bool {
+	for _, fragment := range clientDisconnectMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// errorLogWriter forwards the errors logged by net/http to logrus
+type errorLogWriter struct{}
+
+func (errorLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+	logger := log.WithFields(log.Fields{
+		"prefix": "serve.Server",
+	})
+
+	if isClientDisconnect(msg) {
+		logger.Debug(msg)
+	} else {
+		logger.Error(msg)
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,13 @@
+package serve
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsClientDisconnect(t *testing.T) {
+	require.True(t, isClientDisconnect("http: response.Write on 127.0.0.1:4242->127.0.0.1:5555: write: broken pipe"))
+	require.True(t, isClientDisconnect("read tcp 127.0.0.1:4242: read: connection reset by peer"))
+	require.False(t, isClientDisconnect("http: TLS handshake error from 127.0.0.1:5555: EOF"))
+}
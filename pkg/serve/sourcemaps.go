@@ -0,0 +1,42 @@
+package serve
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// contentTypes overrides the content type of files with these extensions,
+// for types that aren't in the system MIME database everywhere
+var contentTypes = map[string]string{
+	".map": "application/json",
+}
+
+// withContentTypes sets the Content-Type of responses for the extensions in
+// contentTypes. http.FileServer keeps a Content-Type that is already set.
+func withContentTypes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType, ok := contentTypes[path.Ext(r.URL.Path)]; ok {
+			w.Header().Set("Content-Type", contentType)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withSourceMaps points browsers to the source map of JavaScript files with
+// the SourceMap header, when there is a .map file next to them.
+func withSourceMaps(next http.Handler, fs http.FileSystem) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".js") {
+			mapPath := path.Clean("/"+r.URL.Path) + ".map"
+
+			if f, err := fs.Open(mapPath); err == nil {
+				f.Close()
+				w.Header().Set("SourceMap", path.Base(mapPath))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
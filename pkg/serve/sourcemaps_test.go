@@ -0,0 +1,32 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceMaps(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("alert(1)"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js.map"), []byte("{}"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vendor.js"), []byte("alert(2)"), 0600))
+
+	handler := NewHandler(http.Dir(dir), &Config{SourceMaps: true})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	require.Equal(t, "app.js.map", rr.Header().Get("SourceMap"))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/vendor.js", nil))
+	require.Empty(t, rr.Header().Get("SourceMap"))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/app.js.map", nil))
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+}
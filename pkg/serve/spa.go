@@ -0,0 +1,65 @@
+package serve
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+)
+
+// spaIndex is the file served in place of the paths that don't exist
+const spaIndex = "/index.html"
+
+// ValidateSPAStatus checks that status can be used for the SPA fallback
+func ValidateSPAStatus(status int) error {
+	switch status {
+	case http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("invalid SPA status %d, expected 200 or 404", status)
+	}
+}
+
+// withSPAFallback serves the root index.html with the given status for the
+// GET and HEAD requests of paths that don't exist in fs, so that the client
+// side router of single page applications can handle them.
+func withSPAFallback(next http.Handler, fs http.FileSystem, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		f, err := fs.Open(path.Clean("/" + r.URL.Path))
+		if err == nil {
+			f.Close()
+		}
+		if !os.IsNotExist(err) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		index, err := fs.Open(spaIndex)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer index.Close()
+
+		info, err := index.Stat()
+		if err != nil || info.IsDir() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+		w.WriteHeader(status)
+
+		if r.Method != http.MethodHead {
+			io.Copy(w, index)
+		}
+	})
+}
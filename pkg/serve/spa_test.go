@@ -0,0 +1,52 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSPAStatus(t *testing.T) {
+	require.NoError(t, ValidateSPAStatus(http.StatusOK))
+	require.NoError(t, ValidateSPAStatus(http.StatusNotFound))
+	require.Error(t, ValidateSPAStatus(http.StatusFound))
+}
+
+func TestWithSPAFallback(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<div id=app></div>"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.js"), []byte("render()"), 0600))
+
+	for _, status := range []int{http.StatusOK, http.StatusNotFound} {
+		handler := NewHandler(http.Dir(dir), &Config{SPA: true, SPAStatus: status})
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/settings/billing", nil))
+		require.Equal(t, status, rr.Code)
+		require.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+		require.Equal(t, "<div id=app></div>", rr.Body.String())
+
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodHead, "/settings/billing", nil))
+		require.Equal(t, status, rr.Code)
+		require.Empty(t, rr.Body.String())
+
+		// Existing files are served as usual
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "render()", rr.Body.String())
+	}
+}
+
+func TestWithSPAFallbackWithoutIndex(t *testing.T) {
+	handler := NewHandler(http.Dir(t.TempDir()), &Config{SPA: true, SPAStatus: http.StatusOK})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/settings", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
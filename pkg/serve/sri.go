@@ -0,0 +1,102 @@
+package serve
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// sriExtensions are the extensions of the files that can be loaded with a
+// Subresource Integrity check
+var sriExtensions = map[string]bool{
+	".js":  true,
+	".mjs": true,
+	".css": true,
+}
+
+// SRIHash is the Subresource Integrity hash of a served file
+type SRIHash struct {
+	Path      string
+	Integrity string
+}
+
+// SRIHashes returns the sha384 Subresource Integrity hashes of the JavaScript
+// and CSS files in fs, sorted by path. The integrity values are formatted as
+// sha384-<base64> so that they can be used in integrity attributes as is.
+func SRIHashes(fs http.FileSystem) ([]SRIHash, error) {
+	var hashes []SRIHash
+
+	err := walk(fs, "/", func(name string) error {
+		if !sriExtensions[strings.ToLower(path.Ext(name))] {
+			return nil
+		}
+
+		integrity, err := sriHash(fs, name)
+		if err != nil {
+			return err
+		}
+
+		hashes = append(hashes, SRIHash{Path: name, Integrity: integrity})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(hashes, func(i, j int) bool {
+		return hashes[i].Path < hashes[j].Path
+	})
+
+	return hashes, nil
+}
+
+func sriHash(fs http.FileSystem, name string) (string, error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha512.New384()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return "sha384-" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// walk calls fn with the path of every regular file under dir in fs.
+func walk(fs http.FileSystem, dir string, fn func(name string) error) error {
+	f, err := fs.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	infos, err := f.Readdir(-1)
+	f.Close()
+
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		name := path.Join(dir, info.Name())
+
+		if info.IsDir() {
+			err = walk(fs, name, fn)
+		} else if info.Mode().IsRegular() {
+			err = fn(name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
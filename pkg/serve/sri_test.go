@@ -0,0 +1,23 @@
+package serve
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSRIHashes(t *testing.T) {
+	tarFS, err := OpenTar(writeTestTar(t, true))
+	require.NoError(t, err)
+
+	hashes, err := SRIHashes(http.FS(tarFS))
+	require.NoError(t, err)
+	require.Len(t, hashes, 2)
+
+	// echo -n 'alert(1)' | openssl dgst -sha384 -binary | openssl base64 -A
+	require.Equal(t, "/dist/js/app.js", hashes[0].Path)
+	require.Equal(t, "sha384-HT2E9NfWiuQ/w1PRai+hTyqW16NIoCGA/m8VQDUopfAtcz6YQjtsMmQd5uRbVDpW", hashes[0].Integrity)
+
+	require.Equal(t, "/dist/style.css", hashes[1].Path)
+}
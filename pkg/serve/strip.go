@@ -0,0 +1,89 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+)
+
+// strippedFS serves the files of a filesystem with leading path components
+// removed from their name, like tar --strip-components does when extracting.
+type strippedFS struct {
+	fs    http.FileSystem
+	roots []string
+}
+
+// StripComponents returns a filesystem serving the files of fs with their
+// first n path components removed, so that dist/index.html is served as
+// /index.html with n set to 1. When several directories are found n levels
+// deep, a file is looked up in each of them in order and the first match is
+// served. It's an error for fs to have no directory n levels deep, since
+// nothing would be left to serve.
+func StripComponents(fs http.FileSystem, n int) (http.FileSystem, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("the number of components to strip can't be negative")
+	}
+
+	roots := []string{"/"}
+
+	for i := 0; i < n; i++ {
+		var next []string
+
+		for _, root := range roots {
+			dirs, err := subdirectories(fs, root)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, dirs...)
+		}
+
+		if len(next) == 0 {
+			return nil, fmt.Errorf("can't strip %d path components, the directory is only %d levels deep", n, i)
+		}
+
+		roots = next
+	}
+
+	return &strippedFS{fs: fs, roots: roots}, nil
+}
+
+// Open opens name in the first root it exists in
+func (s *strippedFS) Open(name string) (http.File, error) {
+	name = path.Clean("/" + name)
+
+	for _, root := range s.roots {
+		f, err := s.fs.Open(path.Join(root, name))
+		if err == nil {
+			return f, nil
+		}
+	}
+
+	return nil, os.ErrNotExist
+}
+
+// subdirectories returns the sorted paths of the directories in dir
+func subdirectories(fs http.FileSystem, dir string) ([]string, error) {
+	f, err := fs.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, info := range infos {
+		if info.IsDir() {
+			dirs = append(dirs, path.Join(dir, info.Name()))
+		}
+	}
+
+	sort.Strings(dirs)
+
+	return dirs, nil
+}
@@ -0,0 +1,38 @@
+package serve
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripComponents(t *testing.T) {
+	tarFS, err := OpenTar(writeTestTar(t, false))
+	require.NoError(t, err)
+
+	fs, err := StripComponents(http.FS(tarFS), 1)
+	require.NoError(t, err)
+
+	f, err := fs.Open("/js/app.js")
+	require.NoError(t, err)
+	defer f.Close()
+
+	contents, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "alert(1)", string(contents))
+
+	_, err = fs.Open("/dist/index.html")
+	require.Error(t, err)
+
+	// Only dist/js is two levels deep
+	fs, err = StripComponents(http.FS(tarFS), 2)
+	require.NoError(t, err)
+
+	_, err = fs.Open("/app.js")
+	require.NoError(t, err)
+
+	_, err = StripComponents(http.FS(tarFS), 3)
+	require.EqualError(t, err, "can't strip 3 path components, the directory is only 2 levels deep")
+}
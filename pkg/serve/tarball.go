@@ -0,0 +1,146 @@
+package serve
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ValidateTarEndpoint checks that endpoint is a path that the tarball of the
+// served files can be downloaded from
+func ValidateTarEndpoint(endpoint string) error {
+	if !strings.HasPrefix(endpoint, "/") || strings.HasSuffix(endpoint, "/") || path.Clean(endpoint) != endpoint {
+		return fmt.Errorf("invalid tarball endpoint %q, expected a path such as /_download.tar.gz", endpoint)
+	}
+
+	return nil
+}
+
+// withTarEndpoint serves all the files in fs as a gzipped tarball at
+// endpoint, except for a file that would be found at endpoint itself. The
+// tarball is written as the files are read, so it's never held in memory.
+func withTarEndpoint(next http.Handler, fs http.FileSystem, endpoint string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != endpoint || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(endpoint)}))
+
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		gz := gzip.NewWriter(w)
+		tw := tar.NewWriter(gz)
+
+		err := writeTar(tw, fs, "/", endpoint)
+		if err == nil {
+			err = tw.Close()
+		}
+		if err == nil {
+			err = gz.Close()
+		}
+
+		// The status was already sent, all that can be done is to cut the
+		// tarball short
+		if err != nil {
+			log.WithFields(log.Fields{
+				"prefix": "serve.withTarEndpoint",
+			}).Debugf("Failed to write the tarball: %v", err)
+		}
+	})
+}
+
+// writeTar adds the contents of dir to tw, in name order, skipping exclude
+func writeTar(tw *tar.Writer, fs http.FileSystem, dir, exclude string) error {
+	f, err := fs.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	infos, err := f.Readdir(-1)
+	f.Close()
+
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+
+	for _, info := range infos {
+		name := path.Join(dir, info.Name())
+		if name == exclude {
+			continue
+		}
+
+		if info.IsDir() {
+			if err := writeTarHeader(tw, info, name+"/"); err != nil {
+				return err
+			}
+
+			if err := writeTar(tw, fs, name, exclude); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := writeTarFile(tw, fs, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeTarFile adds the file at name to tw. Symlinks are followed through fs,
+// and the files that can't be opened, like symlinks out of the served
+// directory, are left out.
+func writeTarFile(tw *tar.Writer, fs http.FileSystem, name string) error {
+	f, err := fs.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+
+	err = writeTarHeader(tw, info, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.CopyN(tw, f, info.Size())
+
+	return err
+}
+
+func writeTarHeader(tw *tar.Writer, info os.FileInfo, name string) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+
+	// Don't leak the owner of the served files
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+	header.Name = strings.TrimPrefix(name, "/")
+
+	return tw.WriteHeader(header)
+}
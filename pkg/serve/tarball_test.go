@@ -0,0 +1,67 @@
+package serve
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTarEndpoint(t *testing.T) {
+	require.NoError(t, ValidateTarEndpoint("/_download.tar.gz"))
+
+	for _, endpoint := range []string{"", "_download.tar.gz", "/downloads/", "/a/../b.tar.gz"} {
+		require.Error(t, ValidateTarEndpoint(endpoint), endpoint)
+	}
+}
+
+func TestTarEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "_download.tar.gz"), []byte("stale"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "assets"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte("console.log(1)"), 0600))
+
+	handler := NewHandler(http.Dir(dir), &Config{TarEndpoint: "/_download.tar.gz"})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/_download.tar.gz", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/gzip", rr.Header().Get("Content-Type"))
+	require.Equal(t, `attachment; filename=_download.tar.gz`, rr.Header().Get("Content-Disposition"))
+
+	gz, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+
+	contents := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		contents[header.Name] = string(data)
+	}
+
+	require.Equal(t, map[string]string{
+		"assets/":       "",
+		"assets/app.js": "console.log(1)",
+		"index.html":    "<html></html>",
+	}, contents)
+
+	// Other paths are served as usual
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "console.log(1)", rr.Body.String())
+}
@@ -0,0 +1,122 @@
+package serve
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultCertHosts are the names the generated certificate is always valid for
+var defaultCertHosts = []string{"localhost", "127.0.0.1", "::1"}
+
+// selfSignedCertValidity is how long a generated certificate is valid for.
+// Certificates are generated every time the server starts so this doesn't
+// need to be long.
+const selfSignedCertValidity = 30 * 24 * time.Hour
+
+var hostnameRegexp = regexp.MustCompile(`^(\*\.)?([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+
+// ValidateCertHost checks that host can be added to the subject alternative
+// names of a certificate, i.e. that it's an IP address or a hostname.
+func ValidateCertHost(host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+
+	if len(host) <= 253 && hostnameRegexp.MatchString(host) {
+		return nil
+	}
+
+	return fmt.Errorf("%s is not a valid hostname or IP address", host)
+}
+
+// SelfSignedCertificate generates a self-signed certificate valid for the
+// local hosts and the given extra hosts.
+func SelfSignedCertificate(hosts []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"Stripe CLI"}, CommonName: "stripe serve"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedCertValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	for _, host := range append(append([]string{}, defaultCertHosts...), hosts...) {
+		err = ValidateCertHost(host)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, strings.ToLower(host))
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
+
+// LoadClientCAs reads the PEM encoded CA certificates in path, which client
+// certificates must be signed by.
+func LoadClientCAs(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM encoded certificate found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// tlsVersions are the TLS versions that can be required with
+// ParseTLSVersion
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion returns the TLS version constant for a version such as 1.2
+func ParseTLSVersion(version string) (uint16, error) {
+	v, ok := tlsVersions[version]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q, expected 1.2 or 1.3", version)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,176 @@
+package serve
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCertHost(t *testing.T) {
+	for _, host := range []string{"localhost", "app.localhost", "*.example.test", "192.168.1.10", "::1"} {
+		require.NoError(t, ValidateCertHost(host), host)
+	}
+
+	for _, host := range []string{"", "-app.localhost", "app..localhost", "http://localhost", "app_1.test"} {
+		require.Error(t, ValidateCertHost(host), host)
+	}
+}
+
+func TestSelfSignedCertificate(t *testing.T) {
+	cert, err := SelfSignedCertificate([]string{"app.localhost", "192.168.1.10"})
+	require.NoError(t, err)
+
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"localhost", "app.localhost"}, parsed.DNSNames)
+	require.Len(t, parsed.IPAddresses, 3)
+	require.True(t, parsed.IPAddresses[2].Equal(net.ParseIP("192.168.1.10")))
+	require.NoError(t, parsed.VerifyHostname("app.localhost"))
+
+	_, err = SelfSignedCertificate([]string{"not a host"})
+	require.Error(t, err)
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	version, err := ParseTLSVersion("1.3")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), version)
+
+	for _, version := range []string{"", "1.1", "TLS1.2"} {
+		_, err = ParseTLSVersion(version)
+		require.Error(t, err, version)
+	}
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	cert, err := SelfSignedCertificate(nil)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client := server.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS12
+
+	_, err = client.Get(server.URL)
+	require.Error(t, err)
+
+	client.Transport.(*http.Transport).TLSClientConfig.MaxVersion = tls.VersionTLS13
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+// clientCertificate generates a CA, written to a PEM file, and a client
+// certificate signed by it
+func clientCertificate(t *testing.T) (string, tls.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	client := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, client, ca, &clientKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0600))
+
+	return path, tls.Certificate{Certificate: [][]byte{clientDER}, PrivateKey: clientKey}
+}
+
+func TestLoadClientCAs(t *testing.T) {
+	path, _ := clientCertificate(t)
+
+	pool, err := LoadClientCAs(path)
+	require.NoError(t, err)
+	require.NotNil(t, pool)
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0600))
+
+	_, err = LoadClientCAs(empty)
+	require.Error(t, err)
+
+	_, err = LoadClientCAs(filepath.Join(t.TempDir(), "missing.pem"))
+	require.Error(t, err)
+}
+
+func TestClientCertificates(t *testing.T) {
+	caPath, clientCert := clientCertificate(t)
+
+	clientCAs, err := LoadClientCAs(caPath)
+	require.NoError(t, err)
+
+	cert, err := SelfSignedCertificate(nil)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client := server.Client()
+
+	_, err = client.Get(server.URL)
+	require.Error(t, err)
+
+	otherCert, err := SelfSignedCertificate(nil)
+	require.NoError(t, err)
+	client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{otherCert}
+
+	_, err = client.Get(server.URL)
+	require.Error(t, err)
+
+	client.Transport.(*http.Transport).TLSClientConfig.Certificates = []tls.Certificate{clientCert}
+	client.Transport.(*http.Transport).CloseIdleConnections()
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
@@ -0,0 +1,56 @@
+package serve
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+)
+
+// PrintTree writes the files and directories in fs to w as an indented tree,
+// sorted by name, under a first line with the given root name.
+func PrintTree(w io.Writer, fs http.FileSystem, root string) error {
+	fmt.Fprintln(w, root)
+
+	return printTree(w, fs, "/", "")
+}
+
+func printTree(w io.Writer, fs http.FileSystem, dir, indent string) error {
+	f, err := fs.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	infos, err := f.Readdir(-1)
+	f.Close()
+
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+
+	for i, info := range infos {
+		branch, childIndent := "├── ", "│   "
+		if i == len(infos)-1 {
+			branch, childIndent = "└── ", "    "
+		}
+
+		if !info.IsDir() {
+			fmt.Fprintf(w, "%s%s%s\n", indent, branch, info.Name())
+			continue
+		}
+
+		fmt.Fprintf(w, "%s%s%s/\n", indent, branch, info.Name())
+
+		err = printTree(w, fs, path.Join(dir, info.Name()), indent+childIndent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package serve
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintTree(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "js", "vendor"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "css"), 0700))
+	for _, name := range []string{"index.html", "js/app.js", "js/vendor/lib.js", "css/app.css"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+
+	var b strings.Builder
+	require.NoError(t, PrintTree(&b, http.Dir(dir), "dist"))
+	require.Equal(t, `dist
+├── css/
+│   └── app.css
+├── index.html
+└── js/
+    ├── app.js
+    └── vendor/
+        └── lib.js
+`, b.String())
+}
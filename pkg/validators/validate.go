@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
@@ -17,6 +18,12 @@ var (
 	ErrAPIKeyNotConfigured = errors.New("you have not configured API keys yet")
 	// ErrDeviceNameNotConfigured is the error returned when the loaded profile is missing the device name property
 	ErrDeviceNameNotConfigured = errors.New("you have not configured your device name yet")
+	// ErrAPIKeyModeMismatch is the error returned when the API key provided through the environment is not for the requested mode
+	ErrAPIKeyModeMismatch = errors.New("the API key set in STRIPE_API_KEY does not match the requested mode (test or live)")
+	// ErrAPIKeyRejected is the error returned when Stripe doesn't accept an API key that is well formed, e.g. because it was revoked
+	ErrAPIKeyRejected = errors.New("the API key was rejected by Stripe, it may have been revoked or rolled")
+	// ErrAPIKeyExpired is the error returned when the stored expiration date of an API key has passed
+	ErrAPIKeyExpired = errors.New("the API key has expired, run `stripe login` to get a new one")
 	// ErrAccountIDNotConfigured is the error returned when the loaded profile is missing the account_id property
 	ErrAccountIDNotConfigured = errors.New("you have not configured your accountID yet")
 )
@@ -99,6 +106,17 @@ func Account(account string) error {
 	return fmt.Errorf("%s is not an acceptable account filter (CONNECT_IN, CONNECT_OUT, SELF)", account)
 }
 
+// ForwardURL validates that a string is an absolute http or https URL that
+// events can be forwarded to.
+func ForwardURL(input string) error {
+	u, err := url.Parse(input)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s is not a valid forward URL, it must be an absolute http or https URL such as http://localhost:4242/webhook", input)
+	}
+
+	return nil
+}
+
 // HTTPMethod validates that a string is an acceptable HTTP method.
 func HTTPMethod(method string) error {
 	methodUpper := strings.ToUpper(method)
@@ -151,6 +169,16 @@ func StatusCode(code string) error {
 	return fmt.Errorf("Provided status code %s is not in the range of acceptable status codes (200's, 400's, 500's)", code)
 }
 
+// Port validates that a provided port is a number between 1 and 65535.
+func Port(port string) error {
+	num, err := strconv.Atoi(port)
+	if err != nil || num < 1 || num > 65535 {
+		return fmt.Errorf("%s is not a valid port, it must be a number between 1 and 65535", port)
+	}
+
+	return nil
+}
+
 // StatusCodeType validates that a provided status code type is one of those
 // used in the Stripe API.
 func StatusCodeType(code string) error {
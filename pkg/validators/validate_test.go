@@ -42,6 +42,16 @@ func TestTestmodeRestrictedAPIKey(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestForwardURL(t *testing.T) {
+	err := ForwardURL("http://localhost:4242/webhook")
+	require.NoError(t, err)
+}
+
+func TestForwardURLInvalid(t *testing.T) {
+	err := ForwardURL("localhost:4242/webhook")
+	require.Equal(t, "localhost:4242/webhook is not a valid forward URL, it must be an absolute http or https URL such as http://localhost:4242/webhook", fmt.Sprintf("%s", err))
+}
+
 func TestHTTPMethod(t *testing.T) {
 	err := HTTPMethod("GET")
 	require.NoError(t, err)
@@ -111,3 +121,16 @@ func TestStatusCodeNotXs(t *testing.T) {
 	err := StatusCodeType("201")
 	require.Equal(t, "Provided status code type 201 is not a valid type (2XX, 4XX, 5XX)", fmt.Sprintf("%s", err))
 }
+
+func TestPort(t *testing.T) {
+	require.NoError(t, Port("1"))
+	require.NoError(t, Port("4242"))
+	require.NoError(t, Port("65535"))
+}
+
+func TestPortInvalid(t *testing.T) {
+	for _, port := range []string{"", "abc", "0", "-1", "65536", "42.5"} {
+		err := Port(port)
+		require.Equal(t, port+" is not a valid port, it must be a number between 1 and 65535", fmt.Sprintf("%s", err))
+	}
+}